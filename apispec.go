@@ -0,0 +1,312 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// apiTagPrefix is the prefix of the relationship tags referencing operations
+// of the API of the destination, e.g. api:GET /articles.
+const apiTagPrefix = "api:"
+
+// httpMethods are the keys of the operations within a path of an OpenAPI
+// specification.
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// A specReader reads the file spec, referenced by an APISpec defined in the
+// project file file.
+type specReader func(file, spec string) ([]byte, error)
+
+// readSpecFile reads a specification from the local file system, relative
+// to the directory of the project file, unless its path is absolute.
+func readSpecFile(file, spec string) ([]byte, error) {
+	if !filepath.IsAbs(spec) {
+		spec = filepath.Join(filepath.Dir(file), filepath.FromSlash(spec))
+	}
+	return os.ReadFile(spec)
+}
+
+// fsSpecReader returns a specReader reading specifications from fsys,
+// relative to the directory of the project file. paths maps the names of the
+// project files to their paths within fsys.
+func fsSpecReader(fsys fs.FS, paths map[string]string) specReader {
+	return func(file, spec string) ([]byte, error) {
+		return fs.ReadFile(fsys, path.Join(path.Dir(paths[file]), spec))
+	}
+}
+
+// isSpecFile reports whether a file of a project directory is an API
+// specification rather than a project file. Any YAML or JSON file counts as
+// a specification, even if no APISpec references it.
+func isSpecFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func parseAPISpec(m *Model, path string, lineno int, value string) {
+	fields := strings.Split(value, "|")
+	if len(fields) < 2 {
+		m.addErr(path, lineno, "APISpec requires 2 elements: Container | Spec Files")
+		return
+	}
+	if len(fields) > 2 {
+		m.addErr(path, lineno, "APISpec requires 2 elements: Container | Spec Files")
+	}
+
+	container := strings.TrimSpace(fields[0])
+	files := nonEmpty(parseTags(fields[1]))
+	if len(files) == 0 {
+		m.addErr(path, lineno, "APISpec requires at least 1 spec file")
+		return
+	}
+
+	if _, ok := m.APISpecs[container]; ok {
+		m.addErr(path, lineno, "APISpec is already defined for Container: "+container)
+		return
+	}
+	m.define("APISpec", container, path, lineno)
+	m.APISpecs[container] = APISpec{Container: container, Files: files, APIs: make([]API, 0)}
+}
+
+// loadAPISpecs reads and parses the specification files of all APISpecs.
+// Files which cannot be read or parsed are reported at their APISpec.
+func (m *Model) loadAPISpecs(read specReader) {
+	for _, name := range sortedKeys(m.APISpecs) {
+		spec := m.APISpecs[name]
+		pos := m.positions["APISpec:"+name]
+		for _, file := range spec.Files {
+			data, err := read(pos.File, file)
+			if err != nil {
+				m.addErr(pos.File, pos.Line, "APISpec file can not be read: "+err.Error())
+				continue
+			}
+			api, err := parseAPI(data)
+			if err != nil {
+				m.addErr(pos.File, pos.Line, "invalid API specification "+file+": "+err.Error())
+				continue
+			}
+			api.File = file
+			spec.APIs = append(spec.APIs, api)
+		}
+		m.APISpecs[name] = spec
+	}
+}
+
+// parseAPI parses an OpenAPI, Swagger or AsyncAPI specification given as
+// YAML or JSON.
+func parseAPI(data []byte) (API, error) {
+	var doc yaml.MapSlice
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return API{}, err
+	}
+
+	info := yamlValue(doc, "info")
+	api := API{Title: yamlString(info, "title"), Version: yamlString(info, "version"), Operations: make([]APIOperation, 0)}
+	switch {
+	case yamlString(doc, "openapi") != "":
+		api.Specification = "OpenAPI " + yamlString(doc, "openapi")
+		api.Operations = openAPIOperations(doc)
+	case yamlString(doc, "swagger") != "":
+		api.Specification = "Swagger " + yamlString(doc, "swagger")
+		api.Operations = openAPIOperations(doc)
+	case strings.HasPrefix(yamlString(doc, "asyncapi"), "2."):
+		api.Specification = "AsyncAPI " + yamlString(doc, "asyncapi")
+		api.Operations = asyncAPI2Operations(doc)
+	case yamlString(doc, "asyncapi") != "":
+		api.Specification = "AsyncAPI " + yamlString(doc, "asyncapi")
+		api.Operations = asyncAPI3Operations(doc)
+	default:
+		return API{}, errors.New("unknown specification, must be OpenAPI, Swagger or AsyncAPI")
+	}
+	return api, nil
+}
+
+// openAPIOperations returns the operations of all paths of an OpenAPI or
+// Swagger specification, in the order of the document.
+func openAPIOperations(doc yaml.MapSlice) []APIOperation {
+	ops := make([]APIOperation, 0)
+	for _, p := range yamlMap(yamlValue(doc, "paths")) {
+		for _, item := range yamlMap(p.Value) {
+			method := strings.ToLower(fmt.Sprint(item.Key))
+			if !contains(httpMethods, method) {
+				continue
+			}
+			ops = append(ops, APIOperation{ID: yamlString(item.Value, "operationId"), Method: strings.ToUpper(method),
+				Path: fmt.Sprint(p.Key), Summary: yamlString(item.Value, "summary")})
+		}
+	}
+	return ops
+}
+
+// asyncAPI2Operations returns the publish and subscribe operations of all
+// channels of an AsyncAPI 2 specification.
+func asyncAPI2Operations(doc yaml.MapSlice) []APIOperation {
+	ops := make([]APIOperation, 0)
+	for _, ch := range yamlMap(yamlValue(doc, "channels")) {
+		for _, item := range yamlMap(ch.Value) {
+			action := fmt.Sprint(item.Key)
+			if action != "publish" && action != "subscribe" {
+				continue
+			}
+			ops = append(ops, APIOperation{ID: yamlString(item.Value, "operationId"), Method: action,
+				Path: fmt.Sprint(ch.Key), Summary: yamlString(item.Value, "summary")})
+		}
+	}
+	return ops
+}
+
+// asyncAPI3Operations returns the operations of an AsyncAPI 3 specification,
+// which refer to their channel, identified by its address if it has one.
+func asyncAPI3Operations(doc yaml.MapSlice) []APIOperation {
+	channels := yamlValue(doc, "channels")
+	ops := make([]APIOperation, 0)
+	for _, item := range yamlMap(yamlValue(doc, "operations")) {
+		ref := yamlString(yamlValue(item.Value, "channel"), "$ref")
+		channel := strings.TrimPrefix(ref, "#/channels/")
+		if address := yamlString(yamlValue(channels, channel), "address"); address != "" {
+			channel = address
+		}
+		ops = append(ops, APIOperation{ID: fmt.Sprint(item.Key), Method: yamlString(item.Value, "action"),
+			Path: channel, Summary: yamlString(item.Value, "summary")})
+	}
+	return ops
+}
+
+// yamlMap returns the entries of a mapping, in the order of the document if
+// it was decoded as yaml.MapSlice.
+func yamlMap(v interface{}) yaml.MapSlice {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		return v
+	case map[interface{}]interface{}:
+		s := make(yaml.MapSlice, 0, len(v))
+		for k, value := range v {
+			s = append(s, yaml.MapItem{Key: k, Value: value})
+		}
+		sort.Slice(s, func(i, j int) bool { return fmt.Sprint(s[i].Key) < fmt.Sprint(s[j].Key) })
+		return s
+	}
+	return nil
+}
+
+// yamlValue returns the value of a key of a mapping, or nil if there is
+// none.
+func yamlValue(v interface{}, key string) interface{} {
+	for _, item := range yamlMap(v) {
+		if fmt.Sprint(item.Key) == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// yamlString returns the value of a key of a mapping as string.
+func yamlString(v interface{}, key string) string {
+	value := yamlValue(v, key)
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// validateAPISpecs checks the containers of APISpecs and resolves the
+// operations referenced by the api: tags of relationships within the APIs
+// of their destination, or the container of their destination.
+func (m *Model) validateAPISpecs() {
+	for _, name := range sortedKeys(m.APISpecs) {
+		if _, ok := m.Containers[name]; !ok {
+			pos := m.positions["APISpec:"+name]
+			m.addErr(pos.File, pos.Line, "Container of APISpec is not defined: "+name)
+		}
+	}
+
+	for i, r := range m.Relationships {
+		refs := make([]string, 0)
+		for _, t := range r.Tags {
+			if ref, ok := strings.CutPrefix(t, apiTagPrefix); ok {
+				refs = append(refs, strings.TrimSpace(ref))
+			}
+		}
+		if len(refs) == 0 {
+			continue
+		}
+		if _, _, ok := m.Element(r.Destination); !ok {
+			// undefined destinations are reported as such.
+			continue
+		}
+
+		pos := m.relPosition(i)
+		container := r.Destination
+		if c, ok := m.Components[container]; ok {
+			container = c.Container
+		}
+		spec, ok := m.APISpecs[container]
+		if !ok {
+			m.addErr(pos.File, pos.Line, "Operation of Relationship is not defined, "+r.Destination+" has no APISpec: "+
+				strings.Join(refs, ", "))
+			continue
+		}
+		if len(spec.APIs) < len(spec.Files) {
+			// the operations may be part of a specification which could not be
+			// loaded, which is reported already.
+			continue
+		}
+
+		ops := make([]APIOperation, 0)
+		for _, ref := range refs {
+			found := spec.operations(ref)
+			if len(found) == 0 {
+				m.addErr(pos.File, pos.Line, "Operation of Relationship is not defined in the API of "+container+": "+ref)
+			}
+			for _, op := range found {
+				if !containsOperation(ops, op) {
+					ops = append(ops, op)
+				}
+			}
+		}
+		m.Relationships[i].Operations = ops
+	}
+}
+
+// operations returns the operations of the APIs referenced by ref, which is
+// either the ID of an operation, its method followed by its path, e.g.
+// GET /articles, or a path or channel for all of its operations.
+func (s APISpec) operations(ref string) []APIOperation {
+	method, path, hasMethod := strings.Cut(ref, " ")
+	path = strings.TrimSpace(path)
+	ops := make([]APIOperation, 0)
+	for _, api := range s.APIs {
+		for _, op := range api.Operations {
+			if op.ID != "" && op.ID == ref || op.Path == ref || hasMethod && strings.EqualFold(op.Method, method) && op.Path == path {
+				ops = append(ops, op)
+			}
+		}
+	}
+	return ops
+}
+
+func containsOperation(ops []APIOperation, op APIOperation) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
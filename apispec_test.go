@@ -0,0 +1,189 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const apiSource = `System = Blog | |
+Container = Blog | Web | | |
+Container = Blog | API | | |
+Component = API | Articles | | |
+Container = Blog | Worker | | |
+Relationship = Web | Reads articles | HTTPS | API | api:listArticles, api:POST /articles
+Relationship = Web | Edits articles | HTTPS | Articles | api:/articles/{id}
+Relationship = Worker | Publishes articles | Kafka | API | api:articles.published, api:send comments
+Relationship = Worker | Reads articles | HTTPS | API | api:GET /drafts
+Relationship = API | Notifies | HTTPS | Web | api:GET /
+APISpec = API | api/openapi.yaml, api/asyncapi.yaml
+APISpec = Search | api/search.json
+`
+
+const openAPISpec = `openapi: 3.0.3
+info:
+  title: Articles API
+  version: "1.2"
+paths:
+  /articles:
+    parameters: []
+    get:
+      operationId: listArticles
+      summary: List all articles
+    post:
+      summary: Create an article
+  /articles/{id}:
+    get:
+      operationId: getArticle
+    delete:
+      operationId: deleteArticle
+`
+
+const asyncAPISpec = `asyncapi: 3.0.0
+info:
+  title: Article Events
+  version: 1.0.0
+channels:
+  published:
+    address: articles.published
+  comments: {}
+operations:
+  onPublished:
+    action: send
+    channel:
+      $ref: '#/channels/published'
+  onComment:
+    action: receive
+    channel:
+      $ref: '#/channels/comments'
+`
+
+func apiProject() fstest.MapFS {
+	return fstest.MapFS{
+		"blog/blog.c4":            {Data: []byte(apiSource)},
+		"blog/api/openapi.yaml":   {Data: []byte(openAPISpec)},
+		"blog/api/asyncapi.yaml":  {Data: []byte(asyncAPISpec)},
+		"blog/api/search.json":    {Data: []byte(`{"swagger": "2.0", "paths": {"/search": {"get": {}}}}`)},
+		"blog/api/unrelated.json": {Data: []byte(`{"a": "b=c"}`)},
+	}
+}
+
+func TestParseAPI(t *testing.T) {
+	api, err := parseAPI([]byte(openAPISpec))
+	assertEqual(t, nil, err, "parseAPI returned an error")
+	assertEqual(t, "OpenAPI 3.0.3", api.Specification, "specification does not match")
+	assertEqual(t, "1.2", api.Version, "version does not match")
+	assertEqual(t, []APIOperation{
+		{ID: "listArticles", Method: "GET", Path: "/articles", Summary: "List all articles"},
+		{Method: "POST", Path: "/articles", Summary: "Create an article"},
+		{ID: "getArticle", Method: "GET", Path: "/articles/{id}"},
+		{ID: "deleteArticle", Method: "DELETE", Path: "/articles/{id}"},
+	}, api.Operations, "OpenAPI operations do not match")
+
+	api, err = parseAPI([]byte(`{"asyncapi": "2.6.0", "channels": {"articles": {"publish": {"operationId": "pub"}, "subscribe": {}}}}`))
+	assertEqual(t, nil, err, "parseAPI returned an error")
+	assertEqual(t, []APIOperation{
+		{ID: "pub", Method: "publish", Path: "articles"},
+		{Method: "subscribe", Path: "articles"},
+	}, api.Operations, "AsyncAPI 2 operations do not match")
+
+	api, err = parseAPI([]byte(asyncAPISpec))
+	assertEqual(t, nil, err, "parseAPI returned an error")
+	assertEqual(t, []APIOperation{
+		{ID: "onPublished", Method: "send", Path: "articles.published"},
+		{ID: "onComment", Method: "receive", Path: "comments"},
+	}, api.Operations, "AsyncAPI 3 operations do not match")
+
+	_, err = parseAPI([]byte("title: no spec\n"))
+	assertEqual(t, "unknown specification, must be OpenAPI, Swagger or AsyncAPI", err.Error(), "unknown specification must fail")
+}
+
+func TestAPISpecOperations(t *testing.T) {
+	m, err := ParseFS(apiProject(), "blog")
+	assertEqual(t, nil, err, "ParseFS returned an error")
+
+	assertEqual(t, 2, len(m.APISpecs["API"].APIs), "APIs were not loaded")
+	assertEqual(t, []APIOperation{
+		{ID: "listArticles", Method: "GET", Path: "/articles", Summary: "List all articles"},
+		{Method: "POST", Path: "/articles", Summary: "Create an article"},
+	}, m.Relationships[0].Operations, "operations of relationship do not match")
+	assertEqual(t, []APIOperation{
+		{ID: "getArticle", Method: "GET", Path: "/articles/{id}"},
+		{ID: "deleteArticle", Method: "DELETE", Path: "/articles/{id}"},
+	}, m.Relationships[1].Operations, "operations of relationship to component do not match")
+	assertEqual(t, 1, len(m.Relationships[2].Operations), "operations of channel do not match")
+
+	expected := []error{
+		parseError{File: "blog/blog.c4", Line: 12, Msg: "Container of APISpec is not defined: Search"},
+		parseError{File: "blog/blog.c4", Line: 8, Msg: "Operation of Relationship is not defined in the API of API: send comments"},
+		parseError{File: "blog/blog.c4", Line: 9, Msg: "Operation of Relationship is not defined in the API of API: GET /drafts"},
+		parseError{File: "blog/blog.c4", Line: 10, Msg: "Operation of Relationship is not defined, Web has no APISpec: GET /"},
+	}
+	for _, err := range expected {
+		assertEqual(t, true, containsError(m.Errors, err), "missing error: "+err.Error())
+	}
+	for _, err := range m.Errors {
+		assertEqual(t, false, strings.Contains(err.Error(), "unrelated"), "specification parsed as project file")
+	}
+}
+
+func TestAPISpecNotFound(t *testing.T) {
+	fsys := apiProject()
+	delete(fsys, "blog/api/asyncapi.yaml")
+	m, err := ParseFS(fsys, "blog")
+	assertEqual(t, nil, err, "ParseFS returned an error")
+
+	assertEqual(t, 1, len(m.APISpecs["API"].APIs), "APIs were not loaded")
+	assertEqual(t, true, strings.HasPrefix(m.Errors[0].Error(), "blog/blog.c4:11: APISpec file can not be read: "),
+		"missing file must fail: "+m.Errors[0].Error())
+	for _, err := range m.Errors {
+		assertEqual(t, false, strings.Contains(err.Error(), "send comments"), "operations must not be checked")
+	}
+}
+
+func TestAPIOperationsRendered(t *testing.T) {
+	m, err := ParseFS(apiProject(), "blog")
+	assertEqual(t, nil, err, "ParseFS returned an error")
+
+	e := relationshipEdge(m.Relationships[0])
+	assertEqual(t, true, strings.Contains(e.Attrs["label"], `<FONT POINT-SIZE="9">GET /articles<BR/>POST /articles</FONT>`),
+		"edge label does not list operations: "+e.Attrs["label"])
+
+	buf := new(bytes.Buffer)
+	err = RenderHTMLReport(buf, m)
+	assertEqual(t, nil, err, "RenderHTMLReport returned an error")
+	html := buf.String()
+	assertEqual(t, true, strings.Contains(html, "Articles API 1.2 [OpenAPI 3.0.3: api/openapi.yaml]"), "report does not list API")
+	assertEqual(t, true, strings.Contains(html, "<li><code>GET /articles</code> List all articles</li>"),
+		"report does not list operation")
+
+	for _, id := range []string{"components/API", "impact/API"} {
+		view, ok := m.View(id)
+		assertEqual(t, true, ok, "view not found: "+id)
+		buf.Reset()
+		err = execPage(buf, pageTemplate, newViewPage(view, m, m.Views()))
+		assertEqual(t, nil, err, "execPage returned an error")
+		html = buf.String()
+		assertEqual(t, true, strings.Contains(html, "API: Articles API 1.2 [OpenAPI 3.0.3: api/openapi.yaml]"), id+" does not list API")
+		assertEqual(t, true, strings.Contains(html, "<li><code>GET /articles</code> List all articles</li>"),
+			id+" does not list operation")
+	}
+
+	view, _ := m.View("components/Web")
+	assertEqual(t, 0, len(newViewPage(view, m, m.Views()).APIs), "view of container without APISpec must not list APIs")
+}
+
+func containsError(errs []error, err error) bool {
+	for _, e := range errs {
+		if e == err {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/urld/blueprint"
+)
+
+// graphFormats renders the graph of a view in the output formats other than
+// html.
+var graphFormats = map[string]func(w io.Writer, view blueprint.View, model blueprint.Model) error{
+	"svg": blueprint.RenderSVG,
+	"pdf": blueprint.RenderPDF,
+	"dot": blueprint.RenderDOT,
+	"png": func(w io.Writer, view blueprint.View, model blueprint.Model) error {
+		return blueprint.RenderPNG(w, view, model, scale)
+	},
+}
+
+// parseFormats parses a comma separated list of output formats.
+func parseFormats(list string) ([]string, error) {
+	result := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, format := range strings.Split(list, ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if _, ok := graphFormats[format]; !ok && format != "html" {
+			return nil, fmt.Errorf("unknown output format: %q", format)
+		}
+		if !seen[format] {
+			result = append(result, format)
+			seen[format] = true
+		}
+	}
+	return result, nil
+}
+
+func hasFormat(format string) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// An output is a single file of the export: a view in a certain format.
+type output struct {
+	view   blueprint.View
+	format string
+}
+
+// file returns the path of the output relative to the output directory. All
+// formats use the same directory structure.
+func (o output) file() string {
+	return o.view.ID() + "." + o.format
+}
+
+// checkFile reports an error if the outputs of a view can not be written to
+// files of their own within the output directory, because the name of the
+// view contains a path separator or refers to a directory.
+func checkFile(view blueprint.View) error {
+	_, name, _ := strings.Cut(view.ID(), "/")
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("%s: view can not be exported, its name is not a valid file name", view.ID())
+	}
+	return nil
+}
+
+// hash returns the hash of everything the output is rendered from.
+func (o output) hash(model blueprint.Model) (string, error) {
+	if o.format == "html" {
+		return blueprint.PageHash(o.view, model)
+	}
+
+	h := sha256.New()
+	_, _ = io.WriteString(h, o.format+"\n")
+	if o.format == "png" {
+		_, _ = io.WriteString(h, strconv.FormatFloat(scale, 'f', -1, 64)+"\n")
+	}
+	err := blueprint.RenderDOT(h, o.view, model)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeGraph renders the graph of the output to filePath. Just like the
+// HTML pages, the output is written as long as graphviz produced any, in
+// which case its complaints are returned as warning.
+func writeGraph(filePath string, o output, model blueprint.Model) (warning error, err error) {
+	buf := new(bytes.Buffer)
+	err = graphFormats[o.format](buf, o.view, model)
+	if err != nil && buf.Len() == 0 {
+		return nil, err
+	}
+	return err, os.WriteFile(filePath, buf.Bytes(), 0644)
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/urld/blueprint"
+)
+
+func TestCheckFile(t *testing.T) {
+	src := "System = Shop | |\nContainer = Shop | Web App | | |\nContainer = Shop | a/b | | |\nContainer = Shop | .. | | |\n"
+	model, err := blueprint.ParseReader("test.c4", strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := make([]string, 0)
+	for _, view := range model.Views() {
+		if checkFile(view) != nil {
+			invalid = append(invalid, view.ID())
+		}
+	}
+	if strings.Join(invalid, ",") != "components/..,components/a/b" {
+		t.Errorf("views with invalid file names: %v, want components/.. and components/a/b", invalid)
+	}
+}
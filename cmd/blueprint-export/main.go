@@ -11,6 +11,10 @@ import (
 	"io"
 	"os"
 	"path"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/urld/blueprint"
 )
@@ -18,80 +22,247 @@ import (
 var (
 	projPath   string
 	outputPath string
+	jobs       int
+	force      bool
+	formats    []string
+	scale      float64
+	singleFile string
+	rev        string
 )
 
 func main() {
+	var formatList string
 	flag.StringVar(&projPath, "project", "", "path to project directory")
 	flag.StringVar(&outputPath, "output", "", "path to output directory")
+	flag.IntVar(&jobs, "j", runtime.NumCPU(), "number of views rendered concurrently")
+	flag.BoolVar(&force, "force", false, "render all views, even if they did not change")
+	flag.StringVar(&formatList, "format", "html", "comma separated list of output formats: html, svg, png, pdf, dot")
+	flag.Float64Var(&scale, "scale", 1, "scale factor of png images")
+	flag.StringVar(&singleFile, "single", "", "path to a single, self-contained HTML report, instead of an output directory")
+	flag.StringVar(&rev, "rev", "", "export the project at this git revision instead of the working tree")
 	flag.Parse()
 
-	if projPath == "" || outputPath == "" {
+	if singleFile != "" && projPath != "" {
+		err := renderReport()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if projPath == "" || outputPath == "" || jobs < 1 || scale <= 0 {
 		flag.Usage()
 		os.Exit(2)
 	}
-
-	err := renderProject()
+	var err error
+	formats, err = parseFormats(formatList)
 	if err != nil {
 		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	errs := renderProject()
+	for _, err := range errs {
+		fmt.Println(err)
+	}
+	if len(errs) > 0 {
 		os.Exit(1)
 	}
 }
 
-func renderProject() error {
-	model, err := blueprint.Parse(projPath)
+func renderProject() []error {
+	start := time.Now()
+	model, err := parseProject()
 	if err != nil {
-		return err
+		return []error{err}
 	}
 
-	err = os.MkdirAll(path.Join(outputPath, "components"), 0755)
-	if err != nil {
-		return err
+	for _, dir := range []string{"components", "containers", "contexts", "impact"} {
+		if dir == "impact" && !hasFormat("html") {
+			continue
+		}
+		err = os.MkdirAll(path.Join(outputPath, dir), 0755)
+		if err != nil {
+			return []error{err}
+		}
 	}
 
-	for name, container := range model.Containers {
-		view := model.NewComponentView(container)
-
-		err := write(path.Join(outputPath, "components", name+".html"), view, model)
+	prev := readManifest()
+	outputs := make([]output, 0)
+	errs := make([]error, 0)
+	for _, view := range model.Views() {
+		err = checkFile(view)
 		if err != nil {
-			return err
+			errs = append(errs, err)
+			continue
+		}
+		for _, format := range formats {
+			outputs = append(outputs, output{view: view, format: format})
 		}
 	}
+	if hasFormat("html") {
+		for _, view := range impactViews(model) {
+			err = checkFile(view)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			outputs = append(outputs, output{view: view, format: "html"})
+		}
+	}
+	results := renderOutputs(outputs, model, prev)
 
-	err = os.MkdirAll(path.Join(outputPath, "containers"), 0755)
+	cur := manifest{Files: make(map[string]string)}
+	rendered, skipped := 0, 0
+	for i, r := range results {
+		switch {
+		case r.err != nil:
+			errs = append(errs, r.err)
+		case r.skipped:
+			skipped++
+		default:
+			rendered++
+		}
+		if r.warning != nil {
+			fmt.Printf("%s: warning: %v\n", outputs[i].file(), r.warning)
+		}
+		if r.err == nil {
+			cur.Files[outputs[i].file()] = r.hash
+		}
+	}
+
+	removed, rmErrs := removeStale(prev, cur)
+	errs = append(errs, rmErrs...)
+
+	err = writeManifest(cur)
 	if err != nil {
-		return err
+		errs = append(errs, err)
+	}
+	if hasFormat("html") {
+		errs = append(errs, writeSite(model)...)
 	}
 
-	for name, system := range model.Systems {
-		view := model.NewContainerView(system)
+	fmt.Printf("rendered %d files, skipped %d unchanged, removed %d stale, %d errors in %v\n",
+		rendered, skipped, removed, len(errs), time.Since(start).Round(time.Millisecond))
+	return errs
+}
 
-		err := write(path.Join(outputPath, "containers", name+".html"), view, model)
-		if err != nil {
-			return err
-		}
+// impactViews returns the impact views of all elements, which are linked by
+// the HTML pages of the other views. They are only exported as HTML pages.
+func impactViews(model blueprint.Model) []blueprint.View {
+	views := make([]blueprint.View, 0)
+	names := make([]string, 0)
+	for name := range model.Personas {
+		names = append(names, name)
+	}
+	for name := range model.Systems {
+		names = append(names, name)
+	}
+	for name := range model.Containers {
+		names = append(names, name)
 	}
+	for name := range model.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		views = append(views, model.NewImpactView(name))
+	}
+	return views
+}
 
-	err = os.MkdirAll(path.Join(outputPath, "contexts"), 0755)
+// parseProject parses the project, either from the working tree or from the
+// selected git revision.
+func parseProject() (blueprint.Model, error) {
+	if rev == "" {
+		return blueprint.Parse(projPath)
+	}
+	return blueprint.ParseRevision(projPath, rev)
+}
+
+// renderReport writes all views of the project into a single HTML file.
+func renderReport() error {
+	model, err := parseProject()
 	if err != nil {
 		return err
 	}
+	return writeFile(singleFile, model, blueprint.RenderHTMLReport)
+}
+
+// writeSite writes the index and search files, which are shared by all HTML
+// pages.
+func writeSite(model blueprint.Model) []error {
+	errs := make([]error, 0)
+	err := writeIndex(path.Join(outputPath, "index.html"), model)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	err = writeFile(path.Join(outputPath, "search.json"), model, blueprint.RenderSearchIndex)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	err = writeFile(path.Join(outputPath, "search.js"), model, blueprint.RenderSearchScript)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
 
-	for name, context := range model.SystemContexts {
-		view := model.NewSystemContextView(context)
+// An outputResult is the outcome of exporting a single output file.
+type outputResult struct {
+	hash    string
+	skipped bool
+	warning error
+	err     error
+}
 
-		err := write(path.Join(outputPath, "contexts", name+".html"), view, model)
-		if err != nil {
-			return err
-		}
+// renderOutputs renders the outputs with at most jobs workers at a time and
+// returns the result of every output. Outputs whose hash matches the one of
+// the previous manifest are skipped, unless force is set.
+func renderOutputs(outputs []output, model blueprint.Model, prev manifest) []outputResult {
+	results := make([]outputResult, len(outputs))
+	sem := make(chan struct{}, jobs)
+
+	var wg sync.WaitGroup
+	for i, o := range outputs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, o output) {
+			defer wg.Done()
+			results[i] = renderOutput(o, model, prev)
+			<-sem
+		}(i, o)
 	}
+	wg.Wait()
+	return results
+}
 
-	view := model.NewGenericSystemContextView()
-	err = write(path.Join(outputPath, "contexts", "index.html"), view, model)
+func renderOutput(o output, model blueprint.Model, prev manifest) outputResult {
+	file := o.file()
+	hash, err := o.hash(model)
 	if err != nil {
-		return err
+		return outputResult{err: fmt.Errorf("%s: %v", file, err)}
 	}
 
-	return nil
+	filePath := path.Join(outputPath, file)
+	if !force && prev.Files[file] == hash && exists(filePath) {
+		return outputResult{hash: hash, skipped: true}
+	}
+
+	if o.format == "html" {
+		return outputResult{hash: hash, err: write(filePath, o.view, model)}
+	}
+	warning, err := writeGraph(filePath, o, model)
+	if err != nil {
+		return outputResult{err: fmt.Errorf("%s: %v", file, err)}
+	}
+	return outputResult{hash: hash, warning: warning}
+}
+
+func exists(filePath string) bool {
+	_, err := os.Stat(filePath)
+	return err == nil
 }
 
 func write(filePath string, view blueprint.View, model blueprint.Model) error {
@@ -103,12 +274,26 @@ func write(filePath string, view blueprint.View, model blueprint.Model) error {
 
 	err = blueprint.RenderHTMLPage(f, view, model)
 	if err != nil {
-		return err
+		return fmt.Errorf("%s: %v", filePath, err)
 	}
 
 	return nil
 }
 
+func writeIndex(filePath string, model blueprint.Model) error {
+	return writeFile(filePath, model, blueprint.RenderHTMLIndex)
+}
+
+func writeFile(filePath string, model blueprint.Model, render func(io.Writer, blueprint.Model) error) error {
+	f, err := os.Create(filePath)
+	defer close(f)
+	if err != nil {
+		return err
+	}
+
+	return render(f, model)
+}
+
 func close(c io.Closer) {
 	_ = c.Close()
 }
@@ -0,0 +1,79 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// manifestFile is the name of the manifest within the output directory.
+const manifestFile = "blueprint-manifest.json"
+
+// A manifest records the hash of every exported file by its path, relative
+// to the output directory. It is used to skip unchanged files and to remove
+// the files of views which no longer exist.
+type manifest struct {
+	Files map[string]string `json:"files"`
+}
+
+// readManifest reads the manifest of the previous export. A missing or
+// unreadable manifest results in an empty one, so that everything is
+// rendered again.
+func readManifest() manifest {
+	m := manifest{Files: make(map[string]string)}
+	data, err := os.ReadFile(path.Join(outputPath, manifestFile))
+	if err != nil {
+		return m
+	}
+	err = json.Unmarshal(data, &m)
+	if err != nil || m.Files == nil {
+		return manifest{Files: make(map[string]string)}
+	}
+	return m
+}
+
+func writeManifest(m manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path.Join(outputPath, manifestFile), append(data, '\n'), 0644)
+}
+
+// removeStale removes all files of the previous manifest which are not part
+// of the current one and returns the number of removed files.
+func removeStale(prev, cur manifest) (int, []error) {
+	removed := 0
+	errs := make([]error, 0)
+	for _, file := range sortedFiles(prev) {
+		// files outside of the output directory are never removed,
+		// even if the manifest was tampered with.
+		if _, ok := cur.Files[file]; ok || !filepath.IsLocal(file) {
+			continue
+		}
+		err := os.Remove(path.Join(outputPath, file))
+		if err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+	return removed, errs
+}
+
+// sortedFiles returns the files of a manifest in lexical order.
+func sortedFiles(m manifest) []string {
+	files := make([]string, 0, len(m.Files))
+	for file := range m.Files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
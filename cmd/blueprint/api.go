@@ -0,0 +1,214 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/urld/blueprint"
+)
+
+// apiElement is the JSON representation of a single element of the model,
+// as returned by /api/elements/{name}.
+type apiElement struct {
+	Kind     string                   `json:"kind"`
+	Name     string                   `json:"name"`
+	Element  interface{}              `json:"element"`
+	Incoming []blueprint.Relationship `json:"incoming"`
+	Outgoing []blueprint.Relationship `json:"outgoing"`
+	APIs     []blueprint.API          `json:"apis"`
+	Views    []string                 `json:"views"`
+}
+
+// apiView is the JSON representation of a view of the model, as returned by
+// /api/views and /api/views/{id}.
+type apiView struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Elements    []string `json:"elements"`
+}
+
+// apiHandler serves the read-only JSON API:
+//
+//	/api/model                   the complete model
+//	/api/elements                names of all elements
+//	/api/elements/{name}         a single element including its relationships and APIs
+//	/api/elements/{name}/impact  what breaks if the element goes down
+//	/api/views                   all views
+//	/api/views/{id}              a single view
+//	/api/views/{id}/dot          graphviz input of a view
+//	/api/views/{id}/svg          rendered view
+//	/api/errors                  errors of the model
+func apiHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	model, err := parseProject()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p := strings.TrimPrefix(r.URL.Path, "/api/")
+	switch {
+	case p == "model":
+		writeJSON(w, model)
+	case p == "errors":
+		writeJSON(w, blueprint.ErrorsJSON(model.Diagnostics()))
+	case p == "elements":
+		writeJSON(w, elementNames(model))
+	case strings.HasPrefix(p, "elements/") && strings.HasSuffix(p, "/impact"):
+		impact, err := model.Impact(strings.TrimSuffix(strings.TrimPrefix(p, "elements/"), "/impact"))
+		if err != nil {
+			http.Error(w, "Element not found.", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, impact)
+	case strings.HasPrefix(p, "elements/"):
+		elem, ok := newAPIElement(model, strings.TrimPrefix(p, "elements/"))
+		if !ok {
+			http.Error(w, "Element not found.", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, elem)
+	case p == "views":
+		views := make([]apiView, 0)
+		for _, v := range model.Views() {
+			views = append(views, newAPIView(v))
+		}
+		writeJSON(w, views)
+	case strings.HasPrefix(p, "views/"):
+		viewHandler(w, model, strings.TrimPrefix(p, "views/"))
+	default:
+		http.Error(w, "Unknown API endpoint.", http.StatusNotFound)
+	}
+}
+
+func viewHandler(w http.ResponseWriter, model blueprint.Model, id string) {
+	format := ""
+	for _, f := range []string{"dot", "svg"} {
+		if strings.HasSuffix(id, "/"+f) {
+			if _, ok := model.View(id); !ok {
+				id = strings.TrimSuffix(id, "/"+f)
+				format = f
+			}
+		}
+	}
+
+	view, ok := model.View(id)
+	if !ok {
+		http.Error(w, "View not found.", http.StatusNotFound)
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	var err error
+	switch format {
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		err = blueprint.RenderDOT(buf, view, model)
+	case "svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		err = blueprint.RenderSVG(buf, view, model)
+	default:
+		writeJSON(w, newAPIView(view))
+		return
+	}
+	// graphviz warnings are ignored, as long as there is some output.
+	if err != nil && buf.Len() == 0 {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = buf.WriteTo(w)
+}
+
+func newAPIView(v blueprint.View) apiView {
+	return apiView{ID: v.ID(), Title: v.Title(), Description: v.Description(), Elements: v.Elements()}
+}
+
+func newAPIElement(model blueprint.Model, name string) (apiElement, bool) {
+	kind, elem, ok := model.Element(name)
+	if !ok {
+		return apiElement{}, false
+	}
+
+	e := apiElement{
+		Kind:     kind,
+		Name:     name,
+		Element:  elem,
+		Incoming: make([]blueprint.Relationship, 0),
+		Outgoing: make([]blueprint.Relationship, 0),
+		APIs:     make([]blueprint.API, 0),
+		Views:    make([]string, 0),
+	}
+	if spec, ok := model.APISpecs[name]; ok {
+		e.APIs = spec.APIs
+	}
+	for _, r := range model.Relationships {
+		if r.Destination == name {
+			e.Incoming = append(e.Incoming, r)
+		}
+		if r.Source == name {
+			e.Outgoing = append(e.Outgoing, r)
+		}
+	}
+	for _, v := range model.Views() {
+		for _, n := range v.Elements() {
+			if n == name {
+				e.Views = append(e.Views, v.ID())
+				break
+			}
+		}
+	}
+	return e, true
+}
+
+func elementNames(model blueprint.Model) map[string][]string {
+	names := map[string][]string{
+		"personas":   make([]string, 0),
+		"systems":    make([]string, 0),
+		"containers": make([]string, 0),
+		"components": make([]string, 0),
+	}
+	for n := range model.Personas {
+		names["personas"] = append(names["personas"], n)
+	}
+	for n := range model.Systems {
+		names["systems"] = append(names["systems"], n)
+	}
+	for n := range model.Containers {
+		names["containers"] = append(names["containers"], n)
+	}
+	for n := range model.Components {
+		names["components"] = append(names["components"], n)
+	}
+	for _, n := range names {
+		sort.Strings(n)
+	}
+	return names
+}
+
+// writeJSON encodes v before writing anything, so that encoding errors can
+// still be reported with an error status.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = buf.WriteTo(w)
+}
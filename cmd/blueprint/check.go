@@ -0,0 +1,174 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/urld/blueprint"
+)
+
+// check reports the errors of a model and exits with status 1 if there are
+// any, so that it can be used in CI pipelines.
+func check(args []string) {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text, json or sarif")
+	warningsAsErrors := flags.Bool("warnings-as-errors", false, "treat warnings as errors")
+	rev := flags.String("rev", "", "check the project at this git revision instead of the working tree")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: blueprint check [flags] <project path>\n\n")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	model, err := parse(flags.Arg(0), *rev)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	diags := blueprint.ErrorsJSON(model.Diagnostics())
+	if *warningsAsErrors {
+		for i := range diags {
+			diags[i].Severity = "error"
+		}
+	}
+
+	switch *format {
+	case "text":
+		err = writeText(os.Stdout, model.Diagnostics(), *warningsAsErrors)
+	case "json":
+		err = writeIndentJSON(os.Stdout, diags)
+	case "sarif":
+		err = writeSARIF(os.Stdout, diags)
+	default:
+		fmt.Fprintln(os.Stderr, "unknown output format: "+*format)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	for _, d := range diags {
+		if d.Severity == "error" {
+			os.Exit(1)
+		}
+	}
+}
+
+// writeText writes the errors in the form file:line: msg. Warnings are marked
+// as such, or as errors if they are treated as errors.
+func writeText(w io.Writer, errs []error, warningsAsErrors bool) error {
+	for _, d := range blueprint.ErrorsJSON(errs) {
+		msg := d.Message
+		if d.Severity == "warning" && warningsAsErrors {
+			msg = "error: " + msg
+		} else if d.Severity == "warning" {
+			msg = "warning: " + msg
+		}
+		if d.File != "" {
+			msg = fmt.Sprintf("%s:%d: %s", d.File, d.Line, msg)
+		}
+		_, err := fmt.Fprintln(w, msg)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeIndentJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// The following types describe the subset of the Static Analysis Results
+// Interchange Format (SARIF) 2.1.0 which is used by blueprint.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri"`
+}
+
+type sarifResult struct {
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+func writeSARIF(w io.Writer, diags []blueprint.ErrorJSON) error {
+	results := make([]sarifResult, 0, len(diags))
+	for _, d := range diags {
+		r := sarifResult{Level: d.Severity, Message: sarifMessage{Text: d.Message}}
+		if d.File != "" {
+			loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(d.File)},
+			}}
+			if d.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{StartLine: d.Line}
+			}
+			r.Locations = []sarifLocation{loc}
+		}
+		results = append(results, r)
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "blueprint", InformationURI: "https://github.com/urld/blueprint"}},
+			Results: results,
+		}},
+	}
+	return writeIndentJSON(w, log)
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urld/blueprint"
+)
+
+// diff reports the changes between two revisions of a project. Like diff(1),
+// it exits with status 1 if there are any changes.
+func diff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	view := flags.String("view", "", "render the view with this ID as SVG, with the changes highlighted")
+	dot := flags.Bool("dot", false, "write the graphviz input of the view instead of SVG")
+	oldRev := flags.String("old-rev", "", "read the old project at this git revision")
+	newRev := flags.String("new-rev", "", "read the new project at this git revision")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: blueprint diff [flags] <old project path> <new project path>\n\n")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	oldModel, err := parse(flags.Arg(0), *oldRev)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	newModel, err := parse(flags.Arg(1), *newRev)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	d := blueprint.Diff(oldModel, newModel)
+
+	switch {
+	case *view != "" && *dot:
+		err = blueprint.RenderDiffDOT(os.Stdout, d, *view)
+	case *view != "":
+		err = blueprint.RenderDiffSVG(os.Stdout, d, *view)
+	case *format == "text":
+		err = writeChanges(os.Stdout, d.Changes)
+	case *format == "json":
+		err = writeIndentJSON(os.Stdout, d)
+	default:
+		fmt.Fprintln(os.Stderr, "unknown output format: "+*format)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if len(d.Changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+// writeChanges writes one line per change, prefixed with +, - or ~ for
+// added, removed and changed, followed by the changed fields.
+func writeChanges(w io.Writer, changes []blueprint.Change) error {
+	prefixes := map[string]string{"added": "+", "removed": "-", "changed": "~"}
+	for _, c := range changes {
+		_, err := fmt.Fprintf(w, "%s %s %s\n", prefixes[c.Status], c.Kind, c.Name)
+		if err != nil {
+			return err
+		}
+		for _, f := range c.Fields {
+			_, err = fmt.Fprintf(w, "\t%s: %s\n", f.Field, fieldChange(f))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func fieldChange(f blueprint.FieldChange) string {
+	if f.Added == nil && f.Removed == nil {
+		return fmt.Sprintf("%q -> %q", f.Old, f.New)
+	}
+	items := make([]string, 0)
+	for _, item := range f.Added {
+		items = append(items, "+"+item)
+	}
+	for _, item := range f.Removed {
+		items = append(items, "-"+item)
+	}
+	return strings.Join(items, " ")
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/urld/blueprint"
+)
+
+// groupFlags collects the values of the repeatable -group flag.
+type groupFlags []blueprint.GoGroup
+
+func (g *groupFlags) String() string {
+	return fmt.Sprint(*g)
+}
+
+func (g *groupFlags) Set(s string) error {
+	group, err := blueprint.ParseGoGroup(s)
+	if err != nil {
+		return err
+	}
+	*g = append(*g, group)
+	return nil
+}
+
+// extractGo writes the components and relationships of the packages of a Go
+// module as project source, to be refined by hand.
+func extractGo(args []string) {
+	var groups groupFlags
+	flags := flag.NewFlagSet("extract-go", flag.ExitOnError)
+	dir := flags.String("C", ".", "directory within the Go module, the packages are relative to")
+	system := flags.String("system", "", "also define the container as part of this system")
+	container := flags.String("container", "", "container of the components (required)")
+	output := flags.String("o", "", "write the project source to this file instead of stdout")
+	flags.Var(&groups, "group", "group the packages matching a pattern into a component: `pattern=Name`, "+
+		"excluded if Name is empty (repeatable, the first match wins)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: blueprint extract-go [flags] -container <name> [packages]\n\n")
+		fmt.Fprintf(os.Stderr, "packages are directories relative to -C, optionally followed by /... (default ./...)\n\n")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if *container == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	src, err := blueprint.ExtractGo(*dir, *system, *container, groups, patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = os.WriteFile(*output, src, 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/urld/blueprint"
+)
+
+// format formats project files like gofmt does for go source files.
+func format(args []string) {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	list := flags.Bool("l", false, "list files whose formatting differs")
+	write := flags.Bool("w", false, "write result to source file instead of stdout")
+	diff := flags.Bool("d", false, "display diffs instead of rewriting files")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: blueprint fmt [flags] <path> ...\n\n")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	exitCode := 0
+	for _, root := range flags.Args() {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// files given explicitly are formatted regardless of their
+			// extension, like gofmt does.
+			if info.IsDir() || path != root && filepath.Ext(path) != ".c4" {
+				return nil
+			}
+			return formatFile(path, info, *list, *write, *diff)
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 2
+		}
+	}
+	os.Exit(exitCode)
+}
+
+func formatFile(path string, info os.FileInfo, list, write, diff bool) error {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	res := blueprint.Format(src)
+
+	if !list && !write && !diff {
+		_, err = os.Stdout.Write(res)
+		return err
+	}
+	if bytes.Equal(src, res) {
+		return nil
+	}
+
+	if list {
+		fmt.Println(path)
+	}
+	if write {
+		err = ioutil.WriteFile(path, res, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+	}
+	if diff {
+		fmt.Print(unifiedDiff(path+".orig", path, src, res))
+	}
+	return nil
+}
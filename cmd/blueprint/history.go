@@ -0,0 +1,59 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urld/blueprint"
+)
+
+// history writes the HTML timeline of a project, based on the commits of the
+// git repository containing it.
+func history(args []string) {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	rev := flags.String("rev", "HEAD", "git revision whose history is shown")
+	view := flags.String("view", "contexts/index", "ID of the view shown at every revision")
+	output := flags.String("o", "", "path to the HTML file, instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: blueprint history [flags] <project path>\n\n")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	revisions, err := blueprint.History(flags.Arg(0), *rev)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if len(revisions) == 0 {
+		fmt.Fprintln(os.Stderr, "no commits found for project: "+flags.Arg(0))
+		os.Exit(1)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	err = blueprint.RenderHTMLHistory(w, revisions, *view)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
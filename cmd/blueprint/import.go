@@ -0,0 +1,124 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/urld/blueprint"
+)
+
+// importers generate the source of a project file from the given external
+// source file, merged with the project.
+var importers = map[string]func(m blueprint.Model, system, path string, src []byte) ([]byte, error){
+	"compose": blueprint.ImportCompose,
+	"k8s":     blueprint.ImportKubernetes,
+}
+
+// importFile imports elements from files of other tools into a project.
+func importFile(args []string) {
+	names := make([]string, 0, len(importers))
+	for name := range importers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(args) == 0 || importers[args[0]] == nil {
+		fmt.Fprintf(os.Stderr, "usage: blueprint import <format> [flags] <file or directory> <project path>\n\n")
+		fmt.Fprintf(os.Stderr, "formats: %v\n", names)
+		os.Exit(2)
+	}
+	kind, importer := args[0], importers[args[0]]
+
+	flags := flag.NewFlagSet("import "+kind, flag.ExitOnError)
+	system := flags.String("system", "", "system of the imported containers (required)")
+	output := flags.String("o", "", "project file to write and merge with, instead of writing to stdout")
+	flags.Usage = func() {
+		arg := "<file>"
+		if kind == "k8s" {
+			arg = "<file or directory>"
+		}
+		fmt.Fprintf(os.Stderr, "usage: blueprint import %s [flags] %s <project path>\n\n", kind, arg)
+		fmt.Fprintf(os.Stderr, "Elements defined in other files of the project are left out, descriptions\n")
+		fmt.Fprintf(os.Stderr, "and tags edited in the output file are kept, as well as all of its other\n")
+		fmt.Fprintf(os.Stderr, "statements and comments.\n\n")
+		if kind == "k8s" {
+			fmt.Fprintf(os.Stderr, "All YAML files of a directory are imported together.\n\n")
+		}
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args[1:])
+	if flags.NArg() != 2 || *system == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	src, err := readYAML(flags.Arg(0), kind == "k8s")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	model, err := blueprint.Parse(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	res, err := importer(model, *system, *output, src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flags.Arg(0), err)
+		os.Exit(2)
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(res)
+	} else {
+		err = os.WriteFile(*output, res, 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
+
+// readYAML reads a file or, if dirs is set, all YAML files within a
+// directory as separate documents of a single stream, e.g. Kubernetes
+// manifests.
+func readYAML(path string, dirs bool) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return os.ReadFile(path)
+	}
+	if !dirs {
+		return nil, fmt.Errorf("%s is a directory, only a single file can be imported", path)
+	}
+
+	var buf bytes.Buffer
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if ext := filepath.Ext(p); ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		buf.WriteString("---\n")
+		buf.Write(content)
+		buf.WriteString("\n")
+		return nil
+	})
+	return buf.Bytes(), err
+}
@@ -0,0 +1,621 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/urld/blueprint"
+)
+
+// lsp runs a language server for project files, which communicates via
+// stdin and stdout.
+func lsp(args []string) {
+	flags := flag.NewFlagSet("lsp", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: blueprint lsp\n\n")
+		fmt.Fprintf(os.Stderr, "Runs a Language Server Protocol server via stdin and stdout.\n")
+	}
+	_ = flags.Parse(args)
+
+	log.SetOutput(os.Stderr)
+	s := newLSPServer(os.Stdin, os.Stdout)
+	err := s.serve()
+	if err != nil && err != io.EOF {
+		log.Println(err)
+		os.Exit(1)
+	}
+	if !s.shutdown {
+		os.Exit(1)
+	}
+}
+
+// lspFile is a project file known to the language server. The content of
+// files opened by the client may differ from the file system.
+type lspFile struct {
+	path   string
+	lines  []string
+	syntax *blueprint.SyntaxFile
+}
+
+type lspServer struct {
+	conn      *rpcConn
+	root      string
+	files     map[string]*lspFile
+	model     blueprint.Model
+	diagnosed map[string]bool
+	shutdown  bool
+}
+
+func newLSPServer(in io.Reader, out io.Writer) *lspServer {
+	return &lspServer{
+		conn:      &rpcConn{in: bufio.NewReader(in), out: out},
+		files:     make(map[string]*lspFile),
+		diagnosed: make(map[string]bool),
+	}
+}
+
+func (s *lspServer) serve() error {
+	for {
+		msg, err := s.conn.read()
+		if err, ok := err.(*rpcError); ok {
+			_ = s.conn.reply(nil, nil, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, err := s.handle(msg.Method, msg.Params)
+		if msg.ID == nil {
+			// notifications are not answered.
+			if err != nil {
+				log.Printf("%s: %v", msg.Method, err)
+			}
+			continue
+		}
+		err = s.conn.reply(msg.ID, result, err)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *lspServer) handle(method string, rawParams json.RawMessage) (interface{}, error) {
+	var params lspPositionParams
+	switch method {
+	case "initialize":
+		var p lspInitializeParams
+		if err := unmarshalParams(rawParams, &p); err != nil {
+			return nil, err
+		}
+		return s.initialize(p)
+	case "initialized":
+		s.update()
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var p lspDidOpenParams
+		if err := unmarshalParams(rawParams, &p); err != nil {
+			return nil, err
+		}
+		s.setFile(uriToPath(p.TextDocument.URI), p.TextDocument.Text)
+		s.update()
+		return nil, nil
+	case "textDocument/didChange":
+		var p lspDidChangeParams
+		if err := unmarshalParams(rawParams, &p); err != nil {
+			return nil, err
+		}
+		if len(p.ContentChanges) > 0 {
+			text := p.ContentChanges[len(p.ContentChanges)-1].Text
+			s.setFile(uriToPath(p.TextDocument.URI), text)
+			s.update()
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p lspDocumentParams
+		if err := unmarshalParams(rawParams, &p); err != nil {
+			return nil, err
+		}
+		s.closeFile(uriToPath(p.TextDocument.URI))
+		s.update()
+		return nil, nil
+	case "textDocument/didSave":
+		return nil, nil
+	case "textDocument/documentSymbol":
+		var p lspDocumentParams
+		if err := unmarshalParams(rawParams, &p); err != nil {
+			return nil, err
+		}
+		return s.documentSymbols(uriToPath(p.TextDocument.URI)), nil
+	case "textDocument/completion":
+		if err := unmarshalParams(rawParams, &params); err != nil {
+			return nil, err
+		}
+		return s.completion(params), nil
+	case "textDocument/hover":
+		if err := unmarshalParams(rawParams, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(params), nil
+	case "textDocument/definition":
+		if err := unmarshalParams(rawParams, &params); err != nil {
+			return nil, err
+		}
+		return s.locations(params, true, false), nil
+	case "textDocument/references":
+		if err := unmarshalParams(rawParams, &params); err != nil {
+			return nil, err
+		}
+		return s.locations(params, params.Context.IncludeDeclaration, true), nil
+	case "textDocument/rename":
+		if err := unmarshalParams(rawParams, &params); err != nil {
+			return nil, err
+		}
+		return s.rename(params)
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not supported: " + method}
+}
+
+func unmarshalParams(raw json.RawMessage, v interface{}) error {
+	err := json.Unmarshal(raw, v)
+	if err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *lspServer) initialize(p lspInitializeParams) (interface{}, error) {
+	s.root = p.RootPath
+	if p.RootURI != "" {
+		s.root = uriToPath(p.RootURI)
+	}
+	if s.root != "" {
+		err := s.loadRoot()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync": 1, // full
+			"completionProvider": map[string]interface{}{
+				"triggerCharacters": []string{"|", ",", "="},
+			},
+			"hoverProvider":          true,
+			"definitionProvider":     true,
+			"referencesProvider":     true,
+			"renameProvider":         true,
+			"documentSymbolProvider": true,
+		},
+		"serverInfo": map[string]string{"name": "blueprint"},
+	}, nil
+}
+
+// loadRoot loads all .c4 files located recursively in the root directory.
+// Hidden directories, e.g. .git, are skipped.
+func (s *lspServer) loadRoot() error {
+	return filepath.Walk(s.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != s.root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".c4" {
+			return nil
+		}
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		s.setFile(path, string(src))
+		return nil
+	})
+}
+
+func (s *lspServer) setFile(path, text string) {
+	s.files[path] = &lspFile{
+		path:   path,
+		lines:  strings.Split(text, "\n"),
+		syntax: blueprint.ParseSyntax(path, []byte(text)),
+	}
+}
+
+// closeFile discards the content of a closed file in favour of the content
+// on the file system.
+func (s *lspServer) closeFile(path string) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		delete(s.files, path)
+		return
+	}
+	s.setFile(path, string(src))
+}
+
+// update rebuilds the model of all files and publishes its diagnostics.
+func (s *lspServer) update() {
+	paths := make([]string, 0, len(s.files))
+	for path := range s.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	syntax := make([]*blueprint.SyntaxFile, 0, len(paths))
+	for _, path := range paths {
+		syntax = append(syntax, s.files[path].syntax)
+	}
+	s.model = blueprint.ParseSyntaxFiles(syntax...)
+
+	diags := make(map[string][]lspDiagnostic)
+	for _, path := range paths {
+		diags[path] = make([]lspDiagnostic, 0)
+	}
+	for path := range s.diagnosed {
+		diags[path] = make([]lspDiagnostic, 0)
+	}
+	for _, e := range blueprint.ErrorsJSON(s.model.Diagnostics()) {
+		if e.File == "" {
+			// errors without location can not be shown in any document.
+			log.Println(e.Message)
+			continue
+		}
+		severity := lspSeverityError
+		if e.Severity == "warning" {
+			severity = lspSeverityWarning
+		}
+		rng := lspRange{}
+		if f, ok := s.files[e.File]; ok && e.Line > 0 {
+			rng = f.lineRange(e.Line - 1)
+		}
+		diags[e.File] = append(diags[e.File], lspDiagnostic{Range: rng, Severity: severity, Source: "blueprint", Message: e.Message})
+	}
+
+	published := make([]string, 0, len(diags))
+	for path := range diags {
+		published = append(published, path)
+	}
+	sort.Strings(published)
+	s.diagnosed = make(map[string]bool)
+	for _, path := range published {
+		d := diags[path]
+		if len(d) > 0 {
+			s.diagnosed[path] = true
+		}
+		err := s.conn.notify("textDocument/publishDiagnostics", lspPublishDiagnosticsParams{URI: pathToURI(path), Diagnostics: d})
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func (s *lspServer) documentSymbols(path string) []lspDocumentSymbol {
+	symbols := make([]lspDocumentSymbol, 0)
+	f, ok := s.files[path]
+	if !ok {
+		return symbols
+	}
+
+	for _, stmt := range f.syntax.Stmts {
+		keyword, ok := blueprint.CanonicalKeyword(stmt.Keyword.Text)
+		if stmt.Kind != blueprint.ElementStmt || !ok {
+			continue
+		}
+		rng := lspRange{Start: f.position(stmt.Start), End: f.position(stmt.End())}
+
+		if keyword == "Relationship" && len(stmt.Fields) > 3 {
+			symbols = append(symbols, lspDocumentSymbol{
+				Name:           stmt.Fields[0].Text + " -> " + stmt.Fields[3].Text,
+				Detail:         stmt.Fields[1].Text,
+				Kind:           lspSymbolKinds[keyword],
+				Range:          rng,
+				SelectionRange: f.tokenRange(stmt.Fields[0]),
+			})
+			continue
+		}
+		for _, name := range stmt.Names() {
+			if name.Def {
+				symbols = append(symbols, lspDocumentSymbol{
+					Name:           name.Text,
+					Detail:         keyword,
+					Kind:           lspSymbolKinds[keyword],
+					Range:          rng,
+					SelectionRange: f.tokenRange(name.Token),
+				})
+			}
+		}
+	}
+	return symbols
+}
+
+func (s *lspServer) completion(p lspPositionParams) []lspCompletionItem {
+	items := make([]lspCompletionItem, 0)
+	f, ok := s.files[uriToPath(p.TextDocument.URI)]
+	if !ok {
+		return items
+	}
+	pos := f.pos(p.Position)
+	stmt := f.stmtAt(pos.Line)
+
+	if stmt == nil || stmt.Kind != blueprint.ElementStmt {
+		for _, keyword := range blueprint.Keywords() {
+			items = append(items, lspCompletionItem{Label: keyword, Kind: lspCompletionKeyword})
+		}
+		return items
+	}
+
+	keyword, _ := blueprint.CanonicalKeyword(stmt.Keyword.Text)
+	field := stmt.FieldAt(pos)
+	kinds := make(map[string]bool)
+	switch {
+	case keyword == "Relationship" && (field == 0 || field == 3),
+		(keyword == "SameRank" || keyword == "Pin") && field == 1,
+		(keyword == "Above" || keyword == "EdgeOptions") && (field == 1 || field == 2),
+		keyword == "DenyRelationship" && (field == 0 || field == 1),
+		(keyword == "ContainerBoundary" || keyword == "RequireTag") && field == 0:
+		kinds = map[string]bool{"Persona": true, "System": true, "Container": true, "Component": true}
+	case keyword == "SystemContext" && (field == 0 || field == 1):
+		kinds["System"] = true
+	case keyword == "Container" && field == 0:
+		kinds["System"] = true
+	case keyword == "Component" && field == 0:
+		kinds["Container"] = true
+	case keyword == "GoPackage" && field == 0:
+		kinds["Component"] = true
+	case keyword == "APISpec" && field == 0:
+		kinds["Container"] = true
+	}
+
+	names := make([]string, 0)
+	for name := range s.model.Personas {
+		names = append(names, name)
+	}
+	for name := range s.model.Systems {
+		names = append(names, name)
+	}
+	for name := range s.model.Containers {
+		names = append(names, name)
+	}
+	for name := range s.model.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		kind, _, _ := s.model.Element(name)
+		if kinds[kind] {
+			items = append(items, lspCompletionItem{
+				Label:         name,
+				Kind:          lspCompletionClass,
+				Detail:        kind,
+				Documentation: s.description(name),
+			})
+		}
+	}
+	return items
+}
+
+func (s *lspServer) hover(p lspPositionParams) interface{} {
+	f, ref, ok := s.nameAt(p)
+	if !ok {
+		return nil
+	}
+
+	var buf strings.Builder
+	if ref.Kind == "SystemContext" {
+		ctx := s.model.SystemContexts[ref.Text]
+		fmt.Fprintf(&buf, "**%s** [SystemContext]\n\n%s", ctx.Name, ctx.Description)
+	} else {
+		kind, elem, ok := s.model.Element(ref.Text)
+		if !ok {
+			return nil
+		}
+		fmt.Fprintf(&buf, "**%s** [%s%s]\n\n%s", ref.Text, kind, technology(elem), s.description(ref.Text))
+	}
+	return lspHover{
+		Contents: lspMarkupContent{Kind: "markdown", Value: buf.String()},
+		Range:    f.tokenRange(ref.Token),
+	}
+}
+
+func (s *lspServer) description(name string) string {
+	_, elem, _ := s.model.Element(name)
+	switch e := elem.(type) {
+	case blueprint.Persona:
+		return e.Description
+	case blueprint.System:
+		return e.Description
+	case blueprint.Container:
+		return e.Description
+	case blueprint.Component:
+		return e.Description
+	}
+	return ""
+}
+
+func technology(elem interface{}) string {
+	tech := ""
+	switch e := elem.(type) {
+	case blueprint.Container:
+		tech = e.Technology
+	case blueprint.Component:
+		tech = e.Technology
+	}
+	if tech == "" {
+		return ""
+	}
+	return ": " + tech
+}
+
+// nameAt returns the name of an element at the position of the request.
+func (s *lspServer) nameAt(p lspPositionParams) (*lspFile, blueprint.NameRef, bool) {
+	f, ok := s.files[uriToPath(p.TextDocument.URI)]
+	if !ok {
+		return nil, blueprint.NameRef{}, false
+	}
+	pos := f.pos(p.Position)
+	stmt := f.stmtAt(pos.Line)
+	if stmt == nil {
+		return nil, blueprint.NameRef{}, false
+	}
+
+	for _, ref := range stmt.Names() {
+		if ref.Contains(pos) {
+			return f, ref, true
+		}
+	}
+	return nil, blueprint.NameRef{}, false
+}
+
+// refsTo returns all names in all files, which refer to the same element as
+// the name ref.
+func (s *lspServer) refsTo(ref blueprint.NameRef, defs, refs bool) map[*lspFile][]blueprint.NameRef {
+	kind := ref.Kind
+	if kind == "" {
+		kind, _, _ = s.model.Element(ref.Text)
+	}
+
+	result := make(map[*lspFile][]blueprint.NameRef)
+	for _, f := range s.files {
+		for _, stmt := range f.syntax.Stmts {
+			for _, r := range stmt.Names() {
+				if r.Text != ref.Text || (r.Def && !defs) || (!r.Def && !refs) {
+					continue
+				}
+				if r.Kind == kind || (r.Kind == "" && kind != "SystemContext") {
+					result[f] = append(result[f], r)
+				}
+			}
+		}
+	}
+	return result
+}
+
+func (s *lspServer) locations(p lspPositionParams, defs, refs bool) []lspLocation {
+	locs := make([]lspLocation, 0)
+	_, ref, ok := s.nameAt(p)
+	if !ok {
+		return locs
+	}
+
+	for f, names := range s.refsTo(ref, defs, refs) {
+		for _, r := range names {
+			locs = append(locs, lspLocation{URI: pathToURI(f.path), Range: f.tokenRange(r.Token)})
+		}
+	}
+	sort.Slice(locs, func(i, j int) bool {
+		if locs[i].URI != locs[j].URI {
+			return locs[i].URI < locs[j].URI
+		}
+		return locs[i].Range.Start.Line < locs[j].Range.Start.Line
+	})
+	return locs
+}
+
+func (s *lspServer) rename(p lspPositionParams) (interface{}, error) {
+	newName := strings.TrimSpace(p.NewName)
+	if newName == "" || strings.ContainsAny(newName, "|,=\\\n") {
+		return nil, &rpcError{Code: rpcRequestFailed, Message: "invalid name: " + p.NewName}
+	}
+	_, ref, ok := s.nameAt(p)
+	if !ok {
+		return nil, &rpcError{Code: rpcRequestFailed, Message: "no element found at the given position"}
+	}
+
+	edit := lspWorkspaceEdit{Changes: make(map[string][]lspTextEdit)}
+	for f, names := range s.refsTo(ref, true, true) {
+		uri := pathToURI(f.path)
+		for _, r := range names {
+			edit.Changes[uri] = append(edit.Changes[uri], lspTextEdit{Range: f.tokenRange(r.Token), NewText: newName})
+		}
+	}
+	return edit, nil
+}
+
+// stmtAt returns the statement located at line, which starts at 1.
+func (f *lspFile) stmtAt(line int) *blueprint.Stmt {
+	for _, stmt := range f.syntax.Stmts {
+		if line >= stmt.Start.Line && line < stmt.Start.Line+len(stmt.Lines) {
+			return stmt
+		}
+	}
+	return nil
+}
+
+// pos converts a LSP position, counting UTF-16 code units, to a position of
+// the syntax tree, counting bytes.
+func (f *lspFile) pos(p lspPosition) blueprint.Pos {
+	if p.Line >= len(f.lines) {
+		return blueprint.Pos{Line: p.Line + 1, Col: 1}
+	}
+	line := f.lines[p.Line]
+	col, units := 0, 0
+	for col < len(line) && units < p.Character {
+		r, size := utf8.DecodeRuneInString(line[col:])
+		units += len(utf16.Encode([]rune{r}))
+		col += size
+	}
+	return blueprint.Pos{Line: p.Line + 1, Col: col + 1}
+}
+
+// position converts a position of the syntax tree to a LSP position.
+func (f *lspFile) position(p blueprint.Pos) lspPosition {
+	if p.Line < 1 || p.Line > len(f.lines) {
+		return lspPosition{Line: max(p.Line-1, 0)}
+	}
+	line := f.lines[p.Line-1]
+	col := min(max(p.Col-1, 0), len(line))
+	return lspPosition{Line: p.Line - 1, Character: len(utf16.Encode([]rune(line[:col])))}
+}
+
+func (f *lspFile) tokenRange(t blueprint.Token) lspRange {
+	return lspRange{Start: f.position(t.Start), End: f.position(t.End)}
+}
+
+func (f *lspFile) lineRange(line int) lspRange {
+	end := 0
+	if line < len(f.lines) {
+		end = len(utf16.Encode([]rune(f.lines[line])))
+	}
+	return lspRange{Start: lspPosition{Line: line}, End: lspPosition{Line: line, Character: end}}
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func pathToURI(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// lspResponse is a message written by the server, either a response or a
+// notification.
+type lspResponse struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// lspSession runs the server on the given messages, until it reaches the
+// end of its input, and returns everything written by the server.
+func lspSession(t *testing.T, msgs ...interface{}) []lspResponse {
+	in := new(bytes.Buffer)
+	conn := &rpcConn{out: in}
+	for _, msg := range msgs {
+		err := conn.write(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := new(bytes.Buffer)
+	err := newLSPServer(in, out).serve()
+	if err != io.EOF {
+		t.Fatalf("serve returned %v, want EOF", err)
+	}
+
+	responses := make([]lspResponse, 0)
+	r := bufio.NewReader(out)
+	for {
+		header, err := textproto.NewReader(r).ReadMIMEHeader()
+		if err == io.EOF {
+			return responses
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, length)
+		_, err = io.ReadFull(r, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp lspResponse
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, resp)
+	}
+}
+
+func lspRequest(id int, method string, params interface{}) interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func lspNotification(method string, params interface{}) interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func TestRPCConnReadInvalidLength(t *testing.T) {
+	for _, length := range []string{"-5", "abc", strconv.Itoa(rpcMaxContentLength + 1)} {
+		in := fmt.Sprintf("Content-Length: %s\r\n\r\n{}", length)
+		conn := &rpcConn{in: bufio.NewReader(strings.NewReader(in))}
+		_, err := conn.read()
+		if err == nil || !strings.Contains(err.Error(), "invalid Content-Length") {
+			t.Errorf("Content-Length %s: got error %v", length, err)
+		}
+	}
+}
+
+func TestLSPServer(t *testing.T) {
+	root := t.TempDir()
+	err := os.WriteFile(filepath.Join(root, "sys.c4"), []byte("System = Blog | |\nContainer = Blog | Web | | |\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the file is only known by the client, and contains an error.
+	open := filepath.Join(root, "components.c4")
+	text := "Container = Blog | API | | |\nComponent = Unknown | Articles | | |\nComponent =  | Users | | |\n"
+
+	responses := lspSession(t,
+		lspRequest(1, "initialize", map[string]interface{}{"rootUri": pathToURI(root)}),
+		lspNotification("initialized", map[string]interface{}{}),
+		lspNotification("textDocument/didOpen", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": pathToURI(open), "text": text},
+		}),
+		lspRequest(2, "textDocument/completion", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": pathToURI(open)},
+			"position":     map[string]interface{}{"line": 2, "character": 12},
+		}),
+		lspRequest(3, "unknown/method", map[string]interface{}{}),
+		lspRequest(4, "shutdown", nil),
+	)
+
+	var initialized, completed, failed, shutdown bool
+	var diags lspPublishDiagnosticsParams
+	for _, resp := range responses {
+		switch {
+		case resp.Method == "textDocument/publishDiagnostics":
+			var p lspPublishDiagnosticsParams
+			if err := json.Unmarshal(resp.Params, &p); err != nil {
+				t.Fatal(err)
+			}
+			if p.URI == pathToURI(open) {
+				diags = p
+			}
+			if !strings.HasPrefix(p.URI, "file://") || strings.HasSuffix(p.URI, "/") {
+				t.Errorf("diagnostics published for %s", p.URI)
+			}
+		case resp.ID == nil:
+			t.Errorf("unexpected notification %s", resp.Method)
+		case *resp.ID == 1:
+			initialized = strings.Contains(string(resp.Result), `"completionProvider"`)
+		case *resp.ID == 2:
+			var items []lspCompletionItem
+			if err := json.Unmarshal(resp.Result, &items); err != nil {
+				t.Fatal(err)
+			}
+			labels := make([]string, 0)
+			for _, item := range items {
+				labels = append(labels, item.Label)
+			}
+			completed = strings.Join(labels, ",") == "API,Web"
+			if !completed {
+				t.Errorf("completion returned %v, want containers API and Web", labels)
+			}
+		case *resp.ID == 3:
+			failed = resp.Error != nil && resp.Error.Code == rpcMethodNotFound
+		case *resp.ID == 4:
+			shutdown = resp.Error == nil
+		}
+	}
+	if !initialized || !completed || !failed || !shutdown {
+		t.Errorf("missing responses: initialize %v, completion %v, unknown method %v, shutdown %v",
+			initialized, completed, failed, shutdown)
+	}
+
+	found := false
+	for _, d := range diags.Diagnostics {
+		if d.Message == "Container of Component is not defined: Unknown" {
+			found = d.Range.Start.Line == 1 && d.Severity == lspSeverityError
+		}
+	}
+	if !found {
+		t.Errorf("missing diagnostic of the opened file: %+v", diags)
+	}
+}
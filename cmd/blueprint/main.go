@@ -8,9 +8,9 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
-	"path"
 	"strings"
 
 	"github.com/pkg/browser"
@@ -19,10 +19,36 @@ import (
 
 var project struct {
 	path string
+	// rev is the git revision the project is read from. If empty, the
+	// project is read from the working tree.
+	rev string
+}
+
+// commands are the subcommands of blueprint. Without a subcommand, blueprint
+// serves the project via HTTP.
+var commands = map[string]func(args []string){
+	"check":      check,
+	"diff":       diff,
+	"extract-go": extractGo,
+	"fmt":        format,
+	"history":    history,
+	"import":     importFile,
+	"lsp":        lsp,
+	"query":      query,
+	"verify-go":  verifyGo,
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			cmd(os.Args[2:])
+			return
+		}
+	}
+
 	addr := flag.String("http", ":8080", "HTTP Service address")
+	flag.StringVar(&project.rev, "rev", "", "serve the project at this git revision instead of the working tree")
+	flag.Usage = usage
 	flag.Parse()
 	if len(flag.Args()) != 1 {
 		fmt.Println("exactly 1 project path required")
@@ -37,6 +63,9 @@ func main() {
 		browser.OpenURL("http://" + *addr)
 	}
 	http.HandleFunc("/", handler)
+	http.HandleFunc("/api/", apiHandler)
+	http.HandleFunc("/search.json", searchHandler(blueprint.RenderSearchIndex, "application/json"))
+	http.HandleFunc("/search.js", searchHandler(blueprint.RenderSearchScript, "application/javascript"))
 	err := http.ListenAndServe(*addr, nil)
 	if err != nil {
 		fmt.Println(err)
@@ -44,8 +73,25 @@ func main() {
 	}
 }
 
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: blueprint [flags] <project path>\n")
+	fmt.Fprintf(os.Stderr, "       blueprint <command> [flags] <project path>\n\n")
+	fmt.Fprintf(os.Stderr, "commands:\n")
+	fmt.Fprintf(os.Stderr, "  check       report errors of the model\n")
+	fmt.Fprintf(os.Stderr, "  diff        report changes between two revisions of a project\n")
+	fmt.Fprintf(os.Stderr, "  extract-go  generate components from the packages of a Go module\n")
+	fmt.Fprintf(os.Stderr, "  fmt         format project files\n")
+	fmt.Fprintf(os.Stderr, "  history     write an HTML timeline of the git history of the project\n")
+	fmt.Fprintf(os.Stderr, "  import      import containers from docker-compose files or Kubernetes manifests\n")
+	fmt.Fprintf(os.Stderr, "  lsp         run a language server via stdin and stdout\n")
+	fmt.Fprintf(os.Stderr, "  query       query relationships and the impact of outages\n")
+	fmt.Fprintf(os.Stderr, "  verify-go   compare the imports of Go packages with the components\n\n")
+	fmt.Fprintf(os.Stderr, "flags:\n")
+	flag.PrintDefaults()
+}
+
 func handler(w http.ResponseWriter, r *http.Request) {
-	model, err := blueprint.Parse(project.path)
+	model, err := parseProject()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -54,33 +100,17 @@ func handler(w http.ResponseWriter, r *http.Request) {
 	var view blueprint.View
 	if r.URL.Path[1:] == "" {
 		view = model.NewGenericSystemContextView()
+	} else if r.URL.Path[1:] == "index.html" {
+		err = blueprint.RenderHTMLIndex(w, model)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
 	} else {
-		viewKind, name := path.Split(r.URL.Path[1:])
-		name = strings.TrimSuffix(name, ".html")
-		switch path.Dir(viewKind) {
-		case "contexts":
-			sysCtx, ok := model.SystemContexts[name]
-			if !ok {
-				http.Error(w, "Model not found.", http.StatusNotFound)
-				return
-			}
-			view = model.NewSystemContextView(sysCtx)
-		case "containers":
-			sys, ok := model.Systems[name]
-			if !ok {
-				http.Error(w, "Model not found.", http.StatusNotFound)
-				return
-			}
-			view = model.NewContainerView(sys)
-		case "components":
-			container, ok := model.Containers[name]
-			if !ok {
-				http.Error(w, "Model not found.", http.StatusNotFound)
-				return
-			}
-			view = model.NewComponentView(container)
-		default:
-			http.Error(w, "Unknown view kind: "+viewKind, http.StatusBadRequest)
+		var ok bool
+		view, ok = model.View(strings.TrimSuffix(r.URL.Path[1:], ".html"))
+		if !ok {
+			http.Error(w, "Model not found.", http.StatusNotFound)
 			return
 		}
 	}
@@ -91,3 +121,33 @@ func handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// parseProject parses the served project, either from the working tree or
+// from the selected git revision.
+func parseProject() (blueprint.Model, error) {
+	return parse(project.path, project.rev)
+}
+
+func parse(path, rev string) (blueprint.Model, error) {
+	if rev == "" {
+		return blueprint.Parse(path)
+	}
+	return blueprint.ParseRevision(path, rev)
+}
+
+func searchHandler(render func(io.Writer, blueprint.Model) error, contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		model, err := parseProject()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		err = render(w, model)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
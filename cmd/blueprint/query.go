@@ -0,0 +1,154 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urld/blueprint"
+)
+
+// queries are the subcommands of blueprint query, with the number of
+// elements they require.
+var queries = map[string]int{
+	"incoming":     1,
+	"outgoing":     1,
+	"dependencies": 1,
+	"dependents":   1,
+	"paths":        2,
+	"impact":       1,
+}
+
+// query answers questions about the relationships of elements, like which
+// elements break if a container goes down.
+func query(args []string) {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	depth := flags.Int("depth", 0, "maximum depth of dependencies and dependents, 0 for no limit")
+	maxLen := flags.Int("max", 10, "maximum length of paths, must be positive")
+	rev := flags.String("rev", "", "query the project at this git revision instead of the working tree")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: blueprint query <query> [flags] <project path> <element> [<element>]\n\n")
+		fmt.Fprintf(os.Stderr, "queries:\n")
+		fmt.Fprintf(os.Stderr, "  incoming      relationships to the element\n")
+		fmt.Fprintf(os.Stderr, "  outgoing      relationships from the element\n")
+		fmt.Fprintf(os.Stderr, "  dependencies  elements the element uses, transitively\n")
+		fmt.Fprintf(os.Stderr, "  dependents    elements using the element, transitively\n")
+		fmt.Fprintf(os.Stderr, "  paths         all paths from the first element to the second one\n")
+		fmt.Fprintf(os.Stderr, "  impact        what breaks if the element goes down\n\n")
+		fmt.Fprintf(os.Stderr, "flags:\n")
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	q := args[0]
+	n, ok := queries[q]
+	_ = flags.Parse(args[1:])
+	if !ok || flags.NArg() != n+1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	model, err := parse(flags.Arg(0), *rev)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	name := flags.Arg(1)
+
+	var result interface{}
+	switch q {
+	case "incoming":
+		result, err = model.Incoming(name)
+	case "outgoing":
+		result, err = model.Outgoing(name)
+	case "dependencies":
+		result, err = model.Dependencies(name, *depth)
+	case "dependents":
+		result, err = model.Dependents(name, *depth)
+	case "paths":
+		result, err = model.Paths(name, flags.Arg(2), *maxLen)
+	case "impact":
+		result, err = model.Impact(name)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch *format {
+	case "text":
+		err = writeQueryResult(os.Stdout, result)
+	case "json":
+		err = writeIndentJSON(os.Stdout, result)
+	default:
+		fmt.Fprintln(os.Stderr, "unknown output format: "+*format)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
+
+func writeQueryResult(w io.Writer, result interface{}) error {
+	lines := make([]string, 0)
+	switch r := result.(type) {
+	case []blueprint.Relationship:
+		for _, rel := range r {
+			lines = append(lines, relationshipLine(rel))
+		}
+	case []blueprint.Dependency:
+		for _, d := range r {
+			lines = append(lines, fmt.Sprintf("%d\t%s\t%s", d.Depth, d.Kind, d.Name))
+		}
+	case [][]blueprint.Relationship:
+		for _, path := range r {
+			var b strings.Builder
+			b.WriteString(path[0].Source)
+			for i, rel := range path {
+				// relationships of elements within the destination
+				// of the previous one name their actual source.
+				if i > 0 && rel.Source != path[i-1].Destination {
+					b.WriteString("/" + rel.Source)
+				}
+				b.WriteString(" -[" + rel.Description + "]-> " + rel.Destination)
+			}
+			lines = append(lines, b.String())
+		}
+	case blueprint.Impact:
+		lines = append(lines, "down: "+strings.Join(r.Down, ", "), "broken:")
+		for _, rel := range r.Broken {
+			lines = append(lines, "\t"+relationshipLine(rel))
+		}
+		lines = append(lines, "affected:")
+		for _, d := range r.Affected {
+			lines = append(lines, fmt.Sprintf("\t%d\t%s\t%s", d.Depth, d.Kind, d.Name))
+		}
+	}
+
+	for _, line := range lines {
+		_, err := fmt.Fprintln(w, line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func relationshipLine(r blueprint.Relationship) string {
+	line := r.Source + " -> " + r.Destination + ": " + r.Description
+	if r.Technology != "" {
+		line += " [" + r.Technology + "]"
+	}
+	return line
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// unifiedDiff returns the differences between a and b in unified diff format,
+// or an empty string if both are equal.
+func unifiedDiff(aName, bName string, a, b []byte) string {
+	if bytes.Equal(a, b) {
+		return ""
+	}
+	aLines := splitLines(a)
+	bLines := splitLines(b)
+	ops := diffLines(aLines, bLines)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", aName, bName)
+
+	for start := 0; start < len(ops); {
+		// find the next change and the end of its hunk, which includes
+		// all changes separated by less than 2*diffContext equal lines.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		end := start
+		for equal := 0; end < len(ops) && equal <= 2*diffContext; end++ {
+			if ops[end].kind == ' ' {
+				equal++
+			} else {
+				equal = 0
+			}
+		}
+		for end > start && ops[end-1].kind == ' ' {
+			end--
+		}
+
+		from := max(start-diffContext, 0)
+		to := min(end+diffContext, len(ops))
+		aStart, bStart, aCnt, bCnt := ops[from].a, ops[from].b, 0, 0
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				aCnt++
+			}
+			if op.kind != '-' {
+				bCnt++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(aStart, aCnt), hunkRange(bStart, bCnt))
+		for _, op := range ops[from:to] {
+			buf.WriteByte(op.kind)
+			buf.WriteString(op.text)
+			buf.WriteByte('\n')
+		}
+		start = to
+	}
+	return buf.String()
+}
+
+func hunkRange(start, cnt int) string {
+	if cnt == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if cnt == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, cnt)
+}
+
+func splitLines(b []byte) []string {
+	s := strings.TrimSuffix(string(b), "\n")
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "\n")
+}
+
+// A diffOp is a single line of a diff. a and b are the indexes of the line
+// within the old and the new text.
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	text string
+	a, b int
+}
+
+// diffLines computes a minimal line diff using the longest common
+// subsequence of both texts, which is found with Hirschberg's algorithm in
+// space linear to the length of the texts.
+func diffLines(a, b []string) []diffOp {
+	return appendDiff(make([]diffOp, 0, len(a)+len(b)), a, b, 0, 0)
+}
+
+// appendDiff appends the diff of a and b to ops. a and b are parts of the
+// complete texts, beginning at the indexes ai and bi.
+func appendDiff(ops []diffOp, a, b []string, ai, bi int) []diffOp {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		ops = append(ops, diffOp{kind: ' ', text: a[0], a: ai, b: bi})
+		a, b, ai, bi = a[1:], b[1:], ai+1, bi+1
+	}
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	aEnd, bEnd := len(a)-suffix, len(b)-suffix
+
+	switch {
+	case aEnd == 1 && indexOf(b[:bEnd], a[0]) != -1:
+		j := indexOf(b[:bEnd], a[0])
+		for k, line := range b[:j] {
+			ops = append(ops, diffOp{kind: '+', text: line, a: ai, b: bi + k})
+		}
+		ops = append(ops, diffOp{kind: ' ', text: a[0], a: ai, b: bi + j})
+		for k, line := range b[j+1 : bEnd] {
+			ops = append(ops, diffOp{kind: '+', text: line, a: ai + 1, b: bi + j + 1 + k})
+		}
+	case aEnd <= 1 || bEnd == 0:
+		for i, line := range a[:aEnd] {
+			ops = append(ops, diffOp{kind: '-', text: line, a: ai + i, b: bi})
+		}
+		for j, line := range b[:bEnd] {
+			ops = append(ops, diffOp{kind: '+', text: line, a: ai + aEnd, b: bi + j})
+		}
+	default:
+		// split a in half, and b where the longest common subsequences of
+		// both halves are the longest in total.
+		mid := aEnd / 2
+		front := lcsLengths(a[:mid], b[:bEnd])
+		back := lcsLengths(reversed(a[mid:aEnd]), reversed(b[:bEnd]))
+		split := 0
+		for j := range front {
+			if front[j]+back[bEnd-j] > front[split]+back[bEnd-split] {
+				split = j
+			}
+		}
+		ops = appendDiff(ops, a[:mid], b[:split], ai, bi)
+		ops = appendDiff(ops, a[mid:aEnd], b[split:bEnd], ai+mid, bi+split)
+	}
+
+	for k := 0; k < suffix; k++ {
+		ops = append(ops, diffOp{kind: ' ', text: a[aEnd+k], a: ai + aEnd + k, b: bi + bEnd + k})
+	}
+	return ops
+}
+
+// lcsLengths returns the lengths of the longest common subsequences of a
+// and all prefixes of b, by the length of the prefix.
+func lcsLengths(a, b []string) []int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for _, line := range a {
+		for j := range b {
+			if line == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
+
+func reversed(lines []string) []string {
+	r := make([]string, len(lines))
+	for i, line := range lines {
+		r[len(lines)-1-i] = line
+	}
+	return r
+}
+
+func indexOf(lines []string, line string) int {
+	for i, l := range lines {
+		if l == line {
+			return i
+		}
+	}
+	return -1
+}
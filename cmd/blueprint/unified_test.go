@@ -0,0 +1,36 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	expected := `--- old
++++ new
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if diff := unifiedDiff("old", "new", []byte(a), []byte(b)); diff != expected {
+		t.Errorf("diff does not match:\n%s", diff)
+	}
+	if diff := unifiedDiff("old", "new", []byte(a), []byte(a)); diff != "" {
+		t.Errorf("diff of equal texts must be empty:\n%s", diff)
+	}
+}
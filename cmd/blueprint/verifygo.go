@@ -0,0 +1,73 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/urld/blueprint"
+)
+
+// verifyGo reports the differences between the imports of Go packages and
+// the relationships of the components they are mapped to. Like check, it
+// exits with status 1 if there are any.
+func verifyGo(args []string) {
+	flags := flag.NewFlagSet("verify-go", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text, json or sarif")
+	dir := flags.String("C", ".", "directory within the Go module, the packages are relative to")
+	rev := flags.String("rev", "", "read the project at this git revision instead of the working tree")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: blueprint verify-go [flags] <project path> [packages]\n\n")
+		fmt.Fprintf(os.Stderr, "packages are directories relative to -C, optionally followed by /... (default ./...)\n\n")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	patterns := flags.Args()[1:]
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	model, err := parse(flags.Arg(0), *rev)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if len(model.GoPackages) == 0 {
+		fmt.Fprintln(os.Stderr, "no Component is mapped to Go packages, see GoPackage")
+		os.Exit(2)
+	}
+	errs, err := blueprint.VerifyGo(model, *dir, patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	switch *format {
+	case "text":
+		err = writeText(os.Stdout, errs, false)
+	case "json":
+		err = writeIndentJSON(os.Stdout, blueprint.ErrorsJSON(errs))
+	case "sarif":
+		err = writeSARIF(os.Stdout, blueprint.ErrorsJSON(errs))
+	default:
+		fmt.Fprintln(os.Stderr, "unknown output format: "+*format)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// composeFile is the subset of a docker-compose file used by ImportCompose.
+// Fields which may be given in several forms, e.g. as list or as map, are
+// decoded generically.
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+}
+
+type composeService struct {
+	Image     string        `yaml:"image"`
+	Build     interface{}   `yaml:"build"`
+	Ports     []interface{} `yaml:"ports"`
+	Networks  interface{}   `yaml:"networks"`
+	DependsOn interface{}   `yaml:"depends_on"`
+	Links     []string      `yaml:"links"`
+}
+
+// ImportCompose generates the source of the project file at path, which
+// contains a Container for every service of a docker-compose file as part
+// of system, and a Relationship for every depends_on and link between the
+// services. The image of a service becomes the technology of its container,
+// its ports and networks become tags like port:8080:80 and network:backend.
+//
+// The result is merged with the project: elements defined in other files are
+// left out, and the descriptions and tags edited in the file itself are
+// kept, so that it can be imported again after changes to the services. All
+// other statements and comments of the file are kept as well.
+func ImportCompose(m Model, system, path string, src []byte) ([]byte, error) {
+	var f composeFile
+	dec := yaml.NewDecoder(bytes.NewReader(src))
+	err := dec.Decode(&f)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if err == nil && dec.Decode(new(interface{})) != io.EOF {
+		return nil, errors.New("docker-compose file must contain a single document")
+	}
+	if len(f.Services) == 0 {
+		return nil, errors.New("no services found in docker-compose file")
+	}
+
+	imp := imported{
+		source:      "import compose",
+		systems:     []System{{Name: system, Tags: []string{}}},
+		tagPrefixes: []string{"port:", "network:"},
+	}
+	for _, name := range sortedKeys(f.Services) {
+		s := f.Services[name]
+		technology := s.Image
+		if technology == "" && s.Build != nil {
+			technology = "Docker"
+		}
+
+		tags := make([]string, 0)
+		for _, p := range s.Ports {
+			port, err := composePort(p)
+			if err != nil {
+				return nil, fmt.Errorf("service %s: %v", name, err)
+			}
+			tags = append(tags, "port:"+port)
+		}
+		for _, n := range yamlNames(s.Networks) {
+			tags = append(tags, "network:"+n)
+		}
+		imp.containers = append(imp.containers, Container{System: system, Name: importField(name),
+			Technology: importField(technology), Tags: tags})
+
+		deps := yamlNames(s.DependsOn)
+		for _, link := range s.Links {
+			// links are given as SERVICE or SERVICE:ALIAS.
+			deps = append(deps, strings.SplitN(link, ":", 2)[0])
+		}
+		for _, dep := range sortedSet(deps) {
+			if _, ok := f.Services[dep]; !ok {
+				return nil, fmt.Errorf("service %s depends on undefined service: %s", name, dep)
+			}
+			imp.relationships = append(imp.relationships, Relationship{Source: importField(name), Description: "Uses",
+				Destination: importField(dep), Tags: []string{}})
+		}
+	}
+	imp.src, err = readImportFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return m.merge(path, imp), nil
+}
+
+// composePort returns a port of a service, given either in the short syntax
+// [HOST:]CONTAINER[/PROTOCOL], optionally prefixed with an IP address, or in
+// the long syntax with target and published port.
+func composePort(p interface{}) (string, error) {
+	switch v := p.(type) {
+	case string:
+		// the IP address is followed by the host and container port.
+		if parts := strings.Split(v, ":"); len(parts) == 3 {
+			v = parts[1] + ":" + parts[2]
+		}
+		return v, nil
+	case int:
+		return fmt.Sprint(v), nil
+	case map[interface{}]interface{}:
+		target, ok := v["target"]
+		if !ok {
+			return "", errors.New("port requires a target")
+		}
+		if published, ok := v["published"]; ok {
+			return fmt.Sprintf("%v:%v", published, target), nil
+		}
+		return fmt.Sprint(target), nil
+	}
+	return "", fmt.Errorf("invalid port: %v", p)
+}
+
+// yamlNames returns the names of a list, or the keys of a map, e.g. the
+// networks of a service given as list, or as map with their options.
+func yamlNames(v interface{}) []string {
+	names := make([]string, 0)
+	switch v := v.(type) {
+	case []interface{}:
+		for _, n := range v {
+			names = append(names, fmt.Sprint(n))
+		}
+	case map[interface{}]interface{}:
+		for n := range v {
+			names = append(names, fmt.Sprint(n))
+		}
+		sort.Strings(names)
+	}
+	return names
+}
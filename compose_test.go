@@ -0,0 +1,104 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const composeSource = `
+services:
+  web:
+    image: nginx:1.25
+    ports: ["8080:80", "127.0.0.1:8443:443"]
+    networks: [front]
+    depends_on: [api]
+  api:
+    build: .
+    ports:
+      - target: 8000
+        published: 8000
+    networks:
+      front: {}
+      back:
+        aliases: [backend]
+    depends_on:
+      db:
+        condition: service_healthy
+  db:
+    image: postgres:16
+    networks: [back]
+  worker:
+    image: example/worker
+    links: ["db:database"]
+`
+
+// importProject creates a project with a hand-written file and a file of a
+// previous import, in which a description, a tag, a comment and elements
+// were added by hand.
+func importProject(t *testing.T) (Model, string) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"hand.c4": "System = Shop | The shop |\nContainer = Shop | db | Hand-written database | PostgreSQL |\n",
+		"compose.c4": `# Imported by blueprint import compose.
+
+# The proxy in front of the shop.
+Container = Shop | web | Serves the shop | nginx:1.24 | frontend, port:80
+Container = Shop | legacy | Removed service | |
+Persona = Customer | Buys things |
+Relationship = web | Proxies to | HTTP | api |
+Relationship = Customer | Visits | HTTPS | web |
+`,
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		assertEqual(t, nil, err, "WriteFile returned an error")
+	}
+	m, err := Parse(dir)
+	assertEqual(t, nil, err, "Parse returned an error")
+	return m, filepath.Join(dir, "compose.c4")
+}
+
+func TestImportCompose(t *testing.T) {
+	m, path := importProject(t)
+
+	src, err := ImportCompose(m, "Shop", path, []byte(composeSource))
+	assertEqual(t, nil, err, "ImportCompose returned an error")
+	assertEqual(t, `# Imported by blueprint import compose.
+
+# The proxy in front of the shop.
+Container    = Shop | web    | Serves the shop | nginx:1.25     | frontend, port:8080:80, port:8443:443, network:front
+Container    = Shop | legacy | Removed service |                |
+Container    = Shop | api    |                 | Docker         | port:8000:8000, network:back, network:front
+Container    = Shop | worker |                 | example/worker |
+Persona      = Customer | Buys things |
+Relationship = web      | Proxies to | HTTP  | api |
+Relationship = Customer | Visits     | HTTPS | web |
+Relationship = api      | Uses       |       | db  |
+Relationship = worker   | Uses       |       | db  |
+`, string(src), "source does not match")
+}
+
+func TestImportComposeNewProject(t *testing.T) {
+	src, err := ImportCompose(*newModel(), "Shop", "", []byte("services:\n  db:\n    image: postgres\n"))
+	assertEqual(t, nil, err, "ImportCompose returned an error")
+	assertEqual(t, `# Imported by blueprint import compose.
+# Descriptions and tags edited in this file are kept when importing again.
+
+System = Shop | |
+
+Container = Shop | db | | postgres |
+`, string(src), "source does not match")
+
+	_, err = ImportCompose(*newModel(), "Shop", "", []byte("services:\n  web:\n    depends_on: [api]\n"))
+	assertEqual(t, "service web depends on undefined service: api", err.Error(), "undefined dependency must fail")
+	_, err = ImportCompose(*newModel(), "Shop", "", []byte("services:\n  db: {}\n---\nservices:\n  web: {}\n"))
+	assertEqual(t, "docker-compose file must contain a single document", err.Error(), "multiple documents must fail")
+	_, err = ImportCompose(*newModel(), "Shop", "", []byte("version: '3'\n"))
+	assertEqual(t, "no services found in docker-compose file", err.Error(), "file without services must fail")
+}
@@ -0,0 +1,387 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	addedColor   = "#2e7d32"
+	removedColor = "#c62828"
+	changedColor = "#f9a825"
+	ghostColor   = "#fdecea"
+)
+
+// diffLegend contains the legend entries of the highlighted changes.
+var diffLegend = map[string]legendEntry{
+	"added":   {Kind: "Added", Description: "Added in the new model", Color: addedColor, BorderColor: addedColor, Style: "element"},
+	"removed": {Kind: "Removed", Description: "Removed from the old model, shown as ghost", Color: removedColor, BorderColor: removedColor, Style: "element"},
+	"changed": {Kind: "Changed", Description: "Changed between the old and the new model", Color: changedColor, BorderColor: changedColor, Style: "element"},
+}
+
+// A Change describes an element, relationship or view which differs between
+// two models. Kind is one of Persona, System, Container, Component,
+// Relationship or View, Status one of added, removed or changed. Only
+// changed elements, relationships and views have Fields.
+type Change struct {
+	Kind   string        `json:"kind"`
+	Name   string        `json:"name"`
+	Status string        `json:"status"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// A FieldChange is a field of an element, relationship or view which
+// differs between two models. The items of list fields, like tags or the
+// elements of views, are compared one by one and reported as Added and
+// Removed instead of Old and New.
+type FieldChange struct {
+	Field   string   `json:"field"`
+	Old     string   `json:"old,omitempty"`
+	New     string   `json:"new,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// A ModelDiff contains the changes from an old to a new model, as returned
+// by Diff.
+type ModelDiff struct {
+	Changes []Change `json:"changes"`
+
+	old, new Model
+	// elements contains the status of all changed elements by name,
+	// relationships the status of the added and changed relationships of
+	// the new model by relationshipKey, and kept the keys of the
+	// relationships of the old model which were not removed.
+	elements      map[string]string
+	relationships map[string]string
+	kept          map[string]bool
+}
+
+// Diff compares two models and returns the added, removed and changed
+// elements, relationships and views of model b with respect to model a.
+// Elements and views are identified by their names and IDs. Relationships
+// are identified by source, destination and description; if the description
+// changed, they are matched by source and destination only.
+func Diff(a, b Model) ModelDiff {
+	d := ModelDiff{
+		Changes:       make([]Change, 0),
+		old:           a,
+		new:           b,
+		elements:      make(map[string]string),
+		relationships: make(map[string]string),
+		kept:          make(map[string]bool),
+	}
+	diffElements(&d, "Persona", a.Personas, b.Personas)
+	diffElements(&d, "System", a.Systems, b.Systems)
+	diffElements(&d, "Container", a.Containers, b.Containers)
+	diffElements(&d, "Component", a.Components, b.Components)
+	for _, c := range d.Changes {
+		// an element which only changed its kind is not removed.
+		if _, _, ok := b.Element(c.Name); c.Status != "removed" || !ok {
+			d.elements[c.Name] = c.Status
+		}
+	}
+
+	d.diffRelationships()
+	diffElements(&d, "View", viewsByID(a), viewsByID(b))
+	return d
+}
+
+// diffElements appends the changes of all elements of a kind, in the order
+// of their names.
+func diffElements[E any](d *ModelDiff, kind string, a, b map[string]E) {
+	names := make(map[string]bool)
+	for name := range a {
+		names[name] = true
+	}
+	for name := range b {
+		names[name] = true
+	}
+
+	for _, name := range sortedKeys(names) {
+		oldElem, inA := a[name]
+		newElem, inB := b[name]
+		switch {
+		case !inA:
+			d.Changes = append(d.Changes, Change{Kind: kind, Name: name, Status: "added"})
+		case !inB:
+			d.Changes = append(d.Changes, Change{Kind: kind, Name: name, Status: "removed"})
+		default:
+			fields := compareFields(diffFields(oldElem), diffFields(newElem))
+			if len(fields) > 0 {
+				d.Changes = append(d.Changes, Change{Kind: kind, Name: name, Status: "changed", Fields: fields})
+			}
+		}
+	}
+}
+
+// diffRelationships appends the changes of all relationships, in the order
+// of their names.
+func (d *ModelDiff) diffRelationships() {
+	a, b := d.old.Relationships, d.new.Relationships
+
+	// match maps the indices of relationships of b to the ones of a.
+	match := make(map[int]int)
+	matched := make(map[int]bool)
+	matchBy := func(key func(Relationship) string) {
+		for j, r := range b {
+			if _, ok := match[j]; ok {
+				continue
+			}
+			for i, o := range a {
+				if !matched[i] && key(o) == key(r) {
+					match[j] = i
+					matched[i] = true
+					break
+				}
+			}
+		}
+	}
+	matchBy(relationshipName)
+	matchBy(func(r Relationship) string { return r.Source + " -> " + r.Destination })
+
+	changes := make([]Change, 0)
+	for j, r := range b {
+		i, ok := match[j]
+		if !ok {
+			changes = append(changes, Change{Kind: "Relationship", Name: relationshipName(r), Status: "added"})
+			d.relationships[relationshipKey(r)] = "added"
+			continue
+		}
+		d.kept[relationshipKey(a[i])] = true
+		fields := compareFields(diffFields(a[i]), diffFields(r))
+		if len(fields) > 0 {
+			changes = append(changes, Change{Kind: "Relationship", Name: relationshipName(r), Status: "changed", Fields: fields})
+			d.relationships[relationshipKey(r)] = "changed"
+		}
+	}
+	for i, r := range a {
+		if !matched[i] {
+			changes = append(changes, Change{Kind: "Relationship", Name: relationshipName(r), Status: "removed"})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	d.Changes = append(d.Changes, changes...)
+}
+
+// relationshipName identifies a relationship in changes.
+func relationshipName(r Relationship) string {
+	if r.Description == "" {
+		return r.Source + " -> " + r.Destination
+	}
+	return r.Source + " -> " + r.Destination + ": " + r.Description
+}
+
+// relationshipKey identifies a relationship by all of its fields.
+func relationshipKey(r Relationship) string {
+	return strings.Join([]string{r.Source, r.Destination, r.Description, r.Technology, strings.Join(r.Tags, ",")}, "\x00")
+}
+
+func viewsByID(m Model) map[string]View {
+	views := make(map[string]View)
+	for _, v := range m.Views() {
+		views[v.ID()] = v
+	}
+	return views
+}
+
+// A diffField is a field which is compared by Diff. Fields with a list
+// are compared item by item.
+type diffField struct {
+	name  string
+	value string
+	list  []string
+}
+
+// diffFields returns the compared fields of an element, relationship or
+// view.
+func diffFields(elem interface{}) []diffField {
+	switch e := elem.(type) {
+	case Persona:
+		return []diffField{{name: "description", value: e.Description}, {name: "tags", list: e.Tags}}
+	case System:
+		return []diffField{{name: "description", value: e.Description}, {name: "tags", list: e.Tags}}
+	case Container:
+		return []diffField{{name: "system", value: e.System}, {name: "description", value: e.Description},
+			{name: "technology", value: e.Technology}, {name: "tags", list: e.Tags}}
+	case Component:
+		return []diffField{{name: "container", value: e.Container}, {name: "description", value: e.Description},
+			{name: "technology", value: e.Technology}, {name: "tags", list: e.Tags}}
+	case Relationship:
+		return []diffField{{name: "description", value: e.Description}, {name: "technology", value: e.Technology},
+			{name: "tags", list: e.Tags}}
+	case View:
+		return []diffField{{name: "title", value: e.Title()}, {name: "description", value: e.Description()},
+			{name: "elements", list: e.Elements()}}
+	}
+	return nil
+}
+
+// compareFields returns the changes of two lists of the same fields.
+func compareFields(a, b []diffField) []FieldChange {
+	changes := make([]FieldChange, 0)
+	for i := range a {
+		if a[i].list != nil || b[i].list != nil {
+			added, removed := missing(a[i].list, b[i].list), missing(b[i].list, a[i].list)
+			if len(added) > 0 || len(removed) > 0 {
+				changes = append(changes, FieldChange{Field: a[i].name, Added: added, Removed: removed})
+			}
+		} else if a[i].value != b[i].value {
+			changes = append(changes, FieldChange{Field: a[i].name, Old: a[i].value, New: b[i].value})
+		}
+	}
+	return changes
+}
+
+// missing returns the sorted items of b which are not contained in a.
+func missing(a, b []string) []string {
+	set := make(map[string]bool)
+	for _, item := range a {
+		set[item] = true
+	}
+	items := make([]string, 0)
+	for _, item := range sortedSet(b) {
+		if !set[item] {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// RenderDiffDOT writes the graphviz input of a view, identified by its ID,
+// with the changes of the diff highlighted.
+func RenderDiffDOT(w io.Writer, d ModelDiff, id string) error {
+	g, err := d.graph(id)
+	if err != nil {
+		return err
+	}
+	return genDot(w, g)
+}
+
+// RenderDiffSVG renders a view, identified by its ID, with the changes of
+// the diff highlighted as SVG graphic, using graphviz.
+func RenderDiffSVG(w io.Writer, d ModelDiff, id string) error {
+	g, err := d.graph(id)
+	if err != nil {
+		return err
+	}
+	return renderGraph(w, g, "-Tsvg")
+}
+
+// graph returns the graph of the view with the given ID of the new model.
+// Added and changed elements and relationships are highlighted. Elements and
+// relationships of the view in the old model, which are not shown anymore,
+// are added as ghosts. Views which only exist in the old model consist of
+// ghosts only.
+func (d ModelDiff) graph(id string) (graph, error) {
+	newView, inNew := d.new.View(id)
+	oldView, inOld := d.old.View(id)
+	if !inNew && !inOld {
+		return graph{}, fmt.Errorf("view not found: %s", id)
+	}
+
+	var g graph
+	statuses := make(map[string]bool)
+	if inNew {
+		g = viewGraph(newView, d.new)
+		for _, nodes := range [][]node{g.CoreNodes, g.TopNodes, g.BottomNodes} {
+			for _, n := range nodes {
+				if status := d.elements[n.Name]; status != "" && status != "removed" {
+					highlight(n.Attrs, status)
+					statuses[status] = true
+				}
+			}
+		}
+		for _, e := range g.Edges {
+			if status := d.relationships[edgeKey(e)]; status != "" {
+				highlight(e.Attrs, status)
+				statuses[status] = true
+			}
+		}
+	}
+
+	if inOld {
+		old := viewGraph(oldView, d.old)
+		if !inNew {
+			g = graph{Title: old.Title, Boundary: old.Boundary, Legend: old.Legend, Layout: old.Layout}
+		}
+
+		shown := make(map[string]bool)
+		for _, nodes := range [][]node{g.CoreNodes, g.TopNodes, g.BottomNodes} {
+			for _, n := range nodes {
+				shown[n.Name] = true
+			}
+		}
+		ghosts := make(map[string]bool)
+		addGhosts := func(nodes []node, oldNodes []node) []node {
+			for _, n := range oldNodes {
+				if !shown[n.Name] {
+					highlight(n.Attrs, "removed")
+					nodes = append(nodes, n)
+					ghosts[n.Name] = true
+					statuses["removed"] = true
+				}
+			}
+			return nodes
+		}
+		g.CoreNodes = addGhosts(g.CoreNodes, old.CoreNodes)
+		g.TopNodes = addGhosts(g.TopNodes, old.TopNodes)
+		g.BottomNodes = addGhosts(g.BottomNodes, old.BottomNodes)
+
+		for _, e := range old.Edges {
+			if !d.kept[edgeKey(e)] || ghosts[e.Source] || ghosts[e.Destination] {
+				highlight(e.Attrs, "removed")
+				g.Edges = append(g.Edges, e)
+				statuses["removed"] = true
+			}
+		}
+	}
+
+	if g.Legend != nil {
+		g.Legend = g.legend()
+		for _, status := range []string{"added", "removed", "changed"} {
+			if statuses[status] {
+				g.Legend = append(g.Legend, diffLegend[status])
+			}
+		}
+	}
+	return g, nil
+}
+
+func edgeKey(e edge) string {
+	if e.rel == nil {
+		return ""
+	}
+	return relationshipKey(*e.rel)
+}
+
+// highlight sets the graphviz attributes of a node or edge with the given
+// status. Removed ones are dashed and faded.
+func highlight(attrs map[string]string, status string) {
+	switch status {
+	case "added":
+		attrs["color"] = addedColor
+		attrs["penwidth"] = "3"
+	case "changed":
+		attrs["color"] = changedColor
+		attrs["penwidth"] = "3"
+	case "removed":
+		attrs["color"] = removedColor
+		attrs["fontcolor"] = removedColor
+		attrs["penwidth"] = "2"
+		if _, ok := attrs["fillcolor"]; ok {
+			attrs["fillcolor"] = ghostColor
+			attrs["style"] = "filled,rounded,dashed"
+		} else {
+			attrs["style"] = "dashed"
+		}
+	}
+}
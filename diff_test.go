@@ -0,0 +1,104 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"strings"
+	"testing"
+)
+
+const diffOld = `System = Sys | A system | core
+Container = Sys | App | Serves pages | Go |
+Container = Sys | DB | Stores data | Postgres |
+Persona = User | Uses the system |
+Relationship = User | Uses | HTTPS | App |
+Relationship = App | Reads | SQL | DB |
+`
+
+const diffNew = `System = Sys | A system | core, public
+Container = Sys | App | Serves pages | Go |
+Container = Sys | Cache | Caches pages | Redis |
+Persona = User | Uses the system |
+Relationship = User | Uses | HTTP/2 | App |
+Relationship = App | Caches pages | RESP | Cache |
+`
+
+func parseString(t *testing.T, src string) Model {
+	m, err := ParseReader("test.c4", strings.NewReader(src))
+	assertEqual(t, nil, err, "ParseReader returned an error")
+	return m
+}
+
+func TestDiff(t *testing.T) {
+	d := Diff(parseString(t, diffOld), parseString(t, diffNew))
+
+	assertEqual(t, []Change{
+		{Kind: "System", Name: "Sys", Status: "changed", Fields: []FieldChange{{Field: "tags", Added: []string{"public"}, Removed: []string{}}}},
+		{Kind: "Container", Name: "Cache", Status: "added"},
+		{Kind: "Container", Name: "DB", Status: "removed"},
+		{Kind: "Relationship", Name: "App -> Cache: Caches pages", Status: "added"},
+		{Kind: "Relationship", Name: "App -> DB: Reads", Status: "removed"},
+		{Kind: "Relationship", Name: "User -> App: Uses", Status: "changed", Fields: []FieldChange{{Field: "technology", Old: "HTTPS", New: "HTTP/2"}}},
+		{Kind: "View", Name: "components/Cache", Status: "added"},
+		{Kind: "View", Name: "components/DB", Status: "removed"},
+		{Kind: "View", Name: "containers/Sys", Status: "changed", Fields: []FieldChange{{Field: "elements", Added: []string{"Cache"}, Removed: []string{"DB"}}}},
+	}, d.Changes, "changes do not match")
+
+	d = Diff(parseString(t, diffOld), parseString(t, diffOld))
+	assertEqual(t, []Change{}, d.Changes, "equal models must not have changes")
+}
+
+func TestDiffRelationshipDescription(t *testing.T) {
+	d := Diff(parseString(t, diffOld), parseString(t, strings.Replace(diffOld, "| Reads |", "| Reads data |", 1)))
+
+	assertEqual(t, []Change{
+		{Kind: "Relationship", Name: "App -> DB: Reads data", Status: "changed", Fields: []FieldChange{{Field: "description", Old: "Reads", New: "Reads data"}}},
+	}, d.Changes, "changes do not match")
+}
+
+func TestDiffGraph(t *testing.T) {
+	d := Diff(parseString(t, diffOld), parseString(t, diffNew))
+
+	g, err := d.graph("containers/Sys")
+	assertEqual(t, nil, err, "graph returned an error")
+	attrs := make(map[string]map[string]string)
+	for _, n := range g.CoreNodes {
+		attrs[n.Name] = n.Attrs
+	}
+	assertEqual(t, []string{"App", "Cache", "DB"}, sortedKeys(attrs), "core nodes do not match")
+	assertEqual(t, "", attrs["App"]["penwidth"], "unchanged node must not be highlighted")
+	assertEqual(t, addedColor, attrs["Cache"]["color"], "added node must be highlighted")
+	assertEqual(t, removedColor, attrs["DB"]["color"], "removed node must be a ghost")
+	assertEqual(t, ghostColor, attrs["DB"]["fillcolor"], "removed node must be a ghost")
+
+	colors := make(map[string]string)
+	for _, e := range g.Edges {
+		colors[e.Source+" -> "+e.Destination] = e.Attrs["color"]
+	}
+	assertEqual(t, map[string]string{
+		"User -> App":  changedColor,
+		"App -> Cache": addedColor,
+		"App -> DB":    removedColor,
+	}, colors, "edges do not match")
+
+	kinds := make([]string, 0)
+	for _, e := range g.Legend {
+		kinds = append(kinds, e.Kind)
+	}
+	assertEqual(t, []string{"Persona", "Container", "Boundary", "Relationship", "Added", "Removed", "Changed"}, kinds,
+		"legend does not match")
+
+	g, err = d.graph("contexts/index")
+	assertEqual(t, nil, err, "graph returned an error")
+	assertEqual(t, changedColor, g.CoreNodes[0].Attrs["color"], "changed node must be highlighted")
+
+	g, err = d.graph("components/DB")
+	assertEqual(t, nil, err, "removed views must be rendered")
+	assertEqual(t, "DB", g.Title, "removed views must be rendered")
+
+	_, err = d.graph("components/Unknown")
+	assertEqual(t, "view not found: components/Unknown", err.Error(), "unknown view must not be rendered")
+}
@@ -0,0 +1,248 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// formatWidth is the maximum width of a formatted line. Elements exceeding
+// it are split into one line per field, with their description wrapped.
+const formatWidth = 120
+
+// descriptionField is the index of the description field of each element.
+var descriptionField = map[string]int{
+	"Persona":       1,
+	"System":        1,
+	"Container":     2,
+	"Component":     2,
+	"Relationship":  1,
+	"SystemContext": 3,
+}
+
+// isListField reports whether the field at index i of an element holds a
+// comma separated list.
+func isListField(keyword string, i, fieldCnt int) bool {
+	switch keyword {
+	case "SystemContext":
+		return i < 2
+	case "SameRank":
+		return i == 1
+	case "Above", "Pin", "DenyRelationship", "ContainerBoundary", "NoCycles", "RequireTag":
+		return false
+	}
+	return i == fieldCnt-1
+}
+
+// Format returns the canonical formatting of the source of a project file:
+//
+//   - keywords are normalized, e.g. Person becomes Persona
+//   - the fields of consecutive elements of the same kind are aligned
+//   - list fields are separated by ", "
+//   - elements exceeding the maximum line width are split into one line per
+//     field, with their description wrapped
+//   - multiple blank lines are collapsed into one
+//
+// Comments and lines which are not understood are kept as they are.
+func Format(src []byte) []byte {
+	f := ParseSyntax("", src)
+
+	blocks := make([][]*Stmt, 0)
+	block := make([]*Stmt, 0)
+	for _, stmt := range f.Stmts {
+		if stmt.Kind == BlankStmt {
+			if len(block) > 0 {
+				blocks = append(blocks, block)
+			}
+			block = make([]*Stmt, 0)
+			continue
+		}
+		block = append(block, stmt)
+	}
+	if len(block) > 0 {
+		blocks = append(blocks, block)
+	}
+
+	var buf bytes.Buffer
+	for i, block := range blocks {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		for _, line := range formatBlock(block) {
+			buf.WriteString(line)
+			buf.WriteString("\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+// formattedStmt is an element of a block which is about to be formatted.
+type formattedStmt struct {
+	keyword string
+	fields  []string
+	split   bool
+}
+
+func formatBlock(block []*Stmt) []string {
+	elems := make(map[*Stmt]*formattedStmt)
+	kwWidth := 0
+	colWidths := make(map[string][]int)
+	for _, stmt := range block {
+		keyword, ok := canonicalKeywords[stmt.Keyword.Text]
+		if stmt.Kind != ElementStmt || !ok {
+			continue
+		}
+
+		e := &formattedStmt{keyword: keyword, fields: make([]string, len(stmt.Fields))}
+		for i, field := range stmt.Fields {
+			e.fields[i] = field.Text
+			if isListField(keyword, i, len(stmt.Fields)) {
+				e.fields[i] = strings.Join(nonEmpty(parseTags(field.Text)), ", ")
+			}
+		}
+		_, hasDescription := descriptionField[keyword]
+		e.split = hasDescription && descriptionField[keyword] < len(e.fields) &&
+			len(keyword+" = "+strings.Join(e.fields, " | ")) > formatWidth
+		elems[stmt] = e
+
+		if len(keyword) > kwWidth {
+			kwWidth = len(keyword)
+		}
+		if e.split {
+			continue
+		}
+		key := columnKey(e)
+		widths := colWidths[key]
+		if widths == nil {
+			widths = make([]int, len(e.fields))
+		}
+		for i, field := range e.fields {
+			if len(field) > widths[i] {
+				widths[i] = len(field)
+			}
+		}
+		colWidths[key] = widths
+	}
+
+	lines := make([]string, 0)
+	for _, stmt := range block {
+		e, ok := elems[stmt]
+		switch {
+		case stmt.Kind == CommentStmt:
+			lines = append(lines, strings.TrimSpace(stmt.Lines[0]))
+		case !ok:
+			for _, line := range stmt.Lines {
+				lines = append(lines, strings.TrimRight(line, " \t"))
+			}
+		case e.split:
+			lines = append(lines, splitElement(e, kwWidth)...)
+		default:
+			lines = append(lines, alignElement(e, kwWidth, colWidths[columnKey(e)]))
+		}
+	}
+	return lines
+}
+
+// columnKey groups the elements whose fields are aligned to each other.
+func columnKey(e *formattedStmt) string {
+	return e.keyword + ":" + strconv.Itoa(len(e.fields))
+}
+
+func alignElement(e *formattedStmt, kwWidth int, widths []int) string {
+	var buf bytes.Buffer
+	buf.WriteString(pad(e.keyword, kwWidth))
+	buf.WriteString(" =")
+	for i, field := range e.fields {
+		if i > 0 {
+			buf.WriteString(" |")
+		}
+		switch {
+		case i == len(e.fields)-1:
+			buf.WriteString(" " + field)
+		case widths[i] > 0:
+			// columns which are empty for all elements are omitted.
+			buf.WriteString(" " + pad(field, widths[i]))
+		}
+	}
+	return strings.TrimRight(buf.String(), " ")
+}
+
+// splitElement formats an element with one line per field:
+//
+//	Persona = Somebody \
+//	        | does something \
+//	        | some tag
+func splitElement(e *formattedStmt, kwWidth int) []string {
+	indent := strings.Repeat(" ", kwWidth+1)
+	lines := []string{pad(e.keyword, kwWidth) + " = " + e.fields[0]}
+	for i, field := range e.fields[1:] {
+		if i+1 != descriptionField[e.keyword] {
+			lines = append(lines, indent+"| "+field)
+			continue
+		}
+
+		limit := formatWidth - len(indent) - len("| ") - len(" \\")
+		for j, part := range wrapText(field, limit) {
+			if j == 0 {
+				lines = append(lines, indent+"| "+part)
+			} else {
+				lines = append(lines, indent+"  "+part)
+			}
+		}
+	}
+
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+		if i < len(lines)-1 {
+			lines[i] += " \\"
+		}
+	}
+	return lines
+}
+
+// wrapText splits text into lines of at most limit characters. Lines are
+// only split at single spaces, which the parser restores when joining
+// continued lines, so that other whitespace within the text is kept. Words
+// which exceed the limit are not split.
+func wrapText(text string, limit int) []string {
+	words := make([]string, 0)
+	for _, part := range strings.Split(text, " ") {
+		n := len(words)
+		if n > 0 {
+			prev := words[n-1]
+			// a space next to other whitespace is kept within the line.
+			if prev == "" || isSpace(prev[len(prev)-1]) || part == "" || isSpace(part[0]) {
+				words[n-1] += " " + part
+				continue
+			}
+		}
+		words = append(words, part)
+	}
+
+	lines := make([]string, 0)
+	line := ""
+	for _, word := range words {
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) > limit:
+			lines = append(lines, line)
+			line = word
+		default:
+			line += " " + word
+		}
+	}
+	return append(lines, line)
+}
+
+func pad(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const unformatted = `
+
+# personas
+Person = Author | Writes articles. |
+Persona = Reader|Reads articles.| reader,  anonymous
+
+
+
+SoftwareSystem = Blog | A blog. |
+Relationship = Author | Writes | | Blog |
+unknown line
+`
+
+const formatted = `# personas
+Persona = Author | Writes articles. |
+Persona = Reader | Reads articles.  | reader, anonymous
+
+System       = Blog | A blog. |
+Relationship = Author | Writes | | Blog |
+unknown line
+`
+
+func TestFormat(t *testing.T) {
+	res := Format([]byte(unformatted))
+	assertEqual(t, formatted, string(res), "formatted source does not match")
+}
+
+func TestFormatSplitsLongElements(t *testing.T) {
+	src := "Persona = Somebody | does something which is described in so many words that it does not fit into a single line, " +
+		"not even a long one | some tag"
+	expected := `Persona = Somebody \
+        | does something which is described in so many words that it does not fit into a single line, not even a long \
+          one \
+        | some tag
+`
+	res := Format([]byte(src))
+	assertEqual(t, expected, string(res), "formatted source does not match")
+}
+
+func TestFormatSplitKeepsWhitespace(t *testing.T) {
+	src := "Persona = Somebody | does  something\twhich is described in so many words that it does not fit into a single line, " +
+		"not even a long  one | some tag"
+	res := Format([]byte(src))
+	assertEqual(t, stmtValues([]byte(src)), stmtValues(res), "formatting changes the description")
+	assertEqual(t, string(res), string(Format(res)), "formatting is not idempotent")
+}
+
+func TestFormatLongLines(t *testing.T) {
+	comment := "# " + strings.Repeat("x", 70000)
+	src := "Persona = Somebody | " + strings.Repeat("word ", 14000) + "|\r\n" + comment
+	res := Format([]byte(src))
+	assertEqual(t, true, strings.HasSuffix(string(res), comment+"\n"), "long comment is not kept")
+	assertEqual(t, stmtValues([]byte(src)), stmtValues(res), "formatting changes the model")
+}
+
+func TestFormatKeepsModel(t *testing.T) {
+	files, err := filepath.Glob("test/*/*.c4")
+	assertEqual(t, nil, err, "Glob returned an error")
+
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		assertEqual(t, nil, err, "ReadFile returned an error")
+
+		res := Format(src)
+		assertEqual(t, string(res), string(Format(res)), "formatting is not idempotent: "+file)
+		assertEqual(t, stmtValues(src), stmtValues(res), "formatting changes the model: "+file)
+	}
+}
+
+func stmtValues(src []byte) []string {
+	values := make([]string, 0)
+	for _, stmt := range ParseSyntax("", src).Stmts {
+		if stmt.Kind == ElementStmt {
+			values = append(values, canonicalKeywords[stmt.Keyword.Text]+" = "+stmt.Value())
+		}
+	}
+	return values
+}
@@ -0,0 +1,903 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A GitRepo is a local git repository. Its objects are read directly from
+// the .git directory, without running git and without network access.
+// Loose objects, packfiles and packed refs are supported.
+type GitRepo struct {
+	// WorkTree is the root directory of the working tree.
+	WorkTree string
+	// gitDir contains HEAD, commonDir the objects and refs, which are
+	// only different for linked working trees.
+	gitDir    string
+	commonDir string
+
+	mu    sync.Mutex
+	packs []*gitPack
+	trees map[string][]gitTreeEntry
+}
+
+// A GitCommit is a commit of a git repository.
+type GitCommit struct {
+	Hash    string
+	Tree    string
+	Parents []string
+	// Author is the name and email address of the author, Time the time
+	// the commit was authored.
+	Author  string
+	Time    time.Time
+	Message string
+}
+
+// Subject returns the first line of the commit message.
+func (c GitCommit) Subject() string {
+	return strings.SplitN(strings.TrimSpace(c.Message), "\n", 2)[0]
+}
+
+// OpenGitRepo opens the git repository containing path, which is searched
+// for in path and all of its parent directories.
+func OpenGitRepo(path string) (*GitRepo, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		gitDir := filepath.Join(dir, ".git")
+		info, err := os.Stat(gitDir)
+		if err == nil {
+			if !info.IsDir() {
+				// linked working trees and submodules refer to their
+				// git directory by a file.
+				gitDir, err = readGitDirFile(gitDir)
+				if err != nil {
+					return nil, err
+				}
+			}
+			return newGitRepo(dir, gitDir)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, fmt.Errorf("not a git repository: %s", path)
+		}
+		dir = parent
+	}
+}
+
+func readGitDirFile(file string) (string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(string(content))
+	if !strings.HasPrefix(line, "gitdir: ") {
+		return "", fmt.Errorf("invalid git directory file: %s", file)
+	}
+	gitDir := strings.TrimPrefix(line, "gitdir: ")
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(file), gitDir)
+	}
+	return gitDir, nil
+}
+
+func newGitRepo(workTree, gitDir string) (*GitRepo, error) {
+	r := &GitRepo{WorkTree: workTree, gitDir: gitDir, commonDir: gitDir, trees: make(map[string][]gitTreeEntry)}
+	content, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err == nil {
+		r.commonDir = strings.TrimSpace(string(content))
+		if !filepath.IsAbs(r.commonDir) {
+			r.commonDir = filepath.Join(gitDir, r.commonDir)
+		}
+	}
+
+	idxFiles, err := filepath.Glob(filepath.Join(r.commonDir, "objects", "pack", "*.idx"))
+	if err != nil {
+		return nil, err
+	}
+	for _, idxFile := range idxFiles {
+		p, err := readPackIndex(idxFile)
+		if err != nil {
+			return nil, err
+		}
+		r.packs = append(r.packs, p)
+	}
+	return r, nil
+}
+
+// ParseRevision parses the project located in path, just like Parse, but
+// reads its files from the given revision of the git repository containing
+// path instead of the working tree. The revision may be a commit hash, an
+// abbreviated one, a branch, a tag or HEAD, optionally followed by ~N or ^
+// to select ancestors. Errors refer to the files as "revision:path".
+func ParseRevision(path, rev string) (Model, error) {
+	r, err := OpenGitRepo(path)
+	if err != nil {
+		return *newModel(), err
+	}
+	root, err := r.relPath(path)
+	if err != nil {
+		return *newModel(), err
+	}
+	hash, err := r.ResolveRevision(rev)
+	if err != nil {
+		return *newModel(), err
+	}
+	return r.parseCommit(hash, root, rev)
+}
+
+// relPath returns the slash separated path of a file within the working
+// tree.
+func (r *GitRepo) relPath(file string) (string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(r.WorkTree, abs)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsLocal(rel) && rel != "." {
+		return "", fmt.Errorf("%s is outside of the repository %s", file, r.WorkTree)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// parseCommit parses the project located in root of the tree of a commit.
+// Errors refer to the files by prefix:path.
+func (r *GitRepo) parseCommit(hash, root, prefix string) (Model, error) {
+	fsys, err := r.FS(hash)
+	if err != nil {
+		return *newModel(), err
+	}
+	return parseFS(fsys, root, func(name string) string {
+		return prefix + ":" + name
+	}, nil)
+}
+
+// ResolveRevision returns the hash of the commit a revision refers to.
+func (r *GitRepo) ResolveRevision(rev string) (string, error) {
+	base, suffix := rev, ""
+	if i := strings.IndexAny(rev, "~^"); i != -1 {
+		base, suffix = rev[:i], rev[i:]
+	}
+
+	hash, err := r.resolveName(base)
+	if err != nil {
+		return "", err
+	}
+	hash, err = r.peel(hash)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", rev, err)
+	}
+
+	for suffix != "" {
+		op := suffix[0]
+		suffix = suffix[1:]
+		n := 1
+		end := strings.IndexAny(suffix, "~^")
+		if end == -1 {
+			end = len(suffix)
+		}
+		if end > 0 {
+			n, err = strconv.Atoi(suffix[:end])
+			if err != nil {
+				return "", fmt.Errorf("invalid revision: %s", rev)
+			}
+			suffix = suffix[end:]
+		}
+
+		steps, parent := n, 1
+		if op == '^' {
+			// rev^N selects the Nth parent, rev~N the Nth
+			// generation of first parents.
+			steps, parent = 1, n
+		}
+		for i := 0; i < steps; i++ {
+			if parent == 0 {
+				break
+			}
+			c, err := r.Commit(hash)
+			if err != nil {
+				return "", err
+			}
+			if len(c.Parents) < parent {
+				return "", fmt.Errorf("revision has no such parent: %s", rev)
+			}
+			hash = c.Parents[parent-1]
+		}
+	}
+	return hash, nil
+}
+
+// resolveName resolves a ref or an object hash. Refs are looked up in the
+// same order as git does.
+func (r *GitRepo) resolveName(name string) (string, error) {
+	if name == "" {
+		name = "HEAD"
+	}
+	for _, ref := range []string{name, "refs/" + name, "refs/tags/" + name, "refs/heads/" + name,
+		"refs/remotes/" + name, "refs/remotes/" + name + "/HEAD"} {
+		hash, ok, err := r.ref(ref, 0)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return hash, nil
+		}
+	}
+
+	if len(name) >= 4 && isHex(name) {
+		return r.expandHash(name)
+	}
+	return "", fmt.Errorf("unknown revision: %s", name)
+}
+
+// ref returns the object hash of a ref, following symbolic refs.
+func (r *GitRepo) ref(name string, depth int) (string, bool, error) {
+	if depth > 5 {
+		return "", false, fmt.Errorf("too many levels of symbolic refs: %s", name)
+	}
+	if strings.Contains(name, "..") {
+		return "", false, nil
+	}
+
+	for _, dir := range []string{r.gitDir, r.commonDir} {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			continue
+		}
+		value := strings.TrimSpace(string(content))
+		if strings.HasPrefix(value, "ref: ") {
+			return r.ref(strings.TrimPrefix(value, "ref: "), depth+1)
+		}
+		if len(value) == 40 && isHex(value) {
+			return value, true, nil
+		}
+	}
+
+	f, err := os.Open(filepath.Join(r.commonDir, "packed-refs"))
+	if os.IsNotExist(err) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 2 && fields[1] == name {
+			return fields[0], true, nil
+		}
+	}
+	return "", false, s.Err()
+}
+
+// expandHash returns the full hash of the only object starting with the
+// given hex prefix.
+func (r *GitRepo) expandHash(prefix string) (string, error) {
+	prefix = strings.ToLower(prefix)
+	found := make(map[string]bool)
+
+	dir := filepath.Join(r.commonDir, "objects", prefix[:2])
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.HasPrefix(prefix[:2]+e.Name(), prefix) {
+			found[prefix[:2]+e.Name()] = true
+		}
+	}
+	for _, p := range r.packs {
+		for i := 0; i < p.count; i++ {
+			if hash := hex.EncodeToString(p.hash(i)); strings.HasPrefix(hash, prefix) {
+				found[hash] = true
+			}
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("unknown revision: %s", prefix)
+	case 1:
+		return sortedKeys(found)[0], nil
+	}
+	return "", fmt.Errorf("ambiguous revision: %s", prefix)
+}
+
+// peel follows annotated tags to the commit they refer to.
+func (r *GitRepo) peel(hash string) (string, error) {
+	for {
+		typ, data, err := r.object(hash)
+		if err != nil {
+			return "", err
+		}
+		switch typ {
+		case "commit":
+			return hash, nil
+		case "tag":
+			hash = strings.TrimPrefix(strings.SplitN(string(data), "\n", 2)[0], "object ")
+		default:
+			return "", fmt.Errorf("not a commit: %s", hash)
+		}
+	}
+}
+
+// Commit reads the commit with the given hash.
+func (r *GitRepo) Commit(hash string) (GitCommit, error) {
+	typ, data, err := r.object(hash)
+	if err != nil {
+		return GitCommit{}, err
+	}
+	if typ != "commit" {
+		return GitCommit{}, fmt.Errorf("not a commit: %s", hash)
+	}
+
+	c := GitCommit{Hash: hash}
+	header, message, _ := strings.Cut(string(data), "\n\n")
+	c.Message = message
+	for _, line := range strings.Split(header, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			c.Tree = value
+		case "parent":
+			c.Parents = append(c.Parents, value)
+		case "author":
+			c.Author, c.Time = parseSignature(value)
+		}
+	}
+	return c, nil
+}
+
+// parseSignature splits "Name <email> 1500000000 +0200" into the name with
+// email address and the time.
+func parseSignature(sig string) (string, time.Time) {
+	i := strings.LastIndex(sig, ">")
+	if i == -1 {
+		return sig, time.Time{}
+	}
+	fields := strings.Fields(sig[i+1:])
+	if len(fields) != 2 {
+		return sig[:i+1], time.Time{}
+	}
+	sec, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return sig[:i+1], time.Time{}
+	}
+	t := time.Unix(sec, 0)
+	if zone, err := time.Parse("-0700", fields[1]); err == nil {
+		t = t.In(zone.Location())
+	}
+	return sig[:i+1], t
+}
+
+// Log returns the commits reachable from the given commit, following first
+// parents only, starting with the commit itself.
+func (r *GitRepo) Log(hash string) ([]GitCommit, error) {
+	commits := make([]GitCommit, 0)
+	for hash != "" {
+		c, err := r.Commit(hash)
+		if err != nil {
+			return commits, err
+		}
+		commits = append(commits, c)
+		hash = ""
+		if len(c.Parents) > 0 {
+			hash = c.Parents[0]
+		}
+	}
+	return commits, nil
+}
+
+// TreeHash returns the hash of the tree or blob located at the slash
+// separated path within the tree of a commit, or an empty string if the path
+// does not exist. Equal hashes mean equal content.
+func (r *GitRepo) TreeHash(commit, name string) (string, error) {
+	c, err := r.Commit(commit)
+	if err != nil {
+		return "", err
+	}
+	e, err := r.lookup(c.Tree, name)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return e.hash, err
+}
+
+// FS returns the file tree of a commit as file system.
+func (r *GitRepo) FS(commit string) (fs.FS, error) {
+	c, err := r.Commit(commit)
+	if err != nil {
+		return nil, err
+	}
+	return gitFS{repo: r, tree: c.Tree}, nil
+}
+
+// object reads the object with the given hash and returns its type and
+// content.
+func (r *GitRepo) object(hash string) (string, []byte, error) {
+	return r.readObject(hash, 0)
+}
+
+// readObject reads an object which is the base of a chain of depth deltas.
+func (r *GitRepo) readObject(hash string, depth int) (string, []byte, error) {
+	if len(hash) != 40 || !isHex(hash) {
+		return "", nil, fmt.Errorf("invalid object hash: %s", hash)
+	}
+
+	f, err := os.Open(filepath.Join(r.commonDir, "objects", hash[:2], hash[2:]))
+	if err == nil {
+		defer f.Close()
+		return readLooseObject(f)
+	}
+
+	bin, _ := hex.DecodeString(hash)
+	for _, p := range r.packs {
+		if offset, ok := p.offset(bin); ok {
+			return r.readPackObject(p, offset, depth)
+		}
+	}
+	return "", nil, fmt.Errorf("object not found: %s", hash)
+}
+
+func readLooseObject(r io.Reader) (string, []byte, error) {
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return "", nil, err
+	}
+	defer zr.Close()
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, err
+	}
+	header, data, ok := bytes.Cut(content, []byte{0})
+	typ, _, _ := strings.Cut(string(header), " ")
+	if !ok || typ == "" {
+		return "", nil, fmt.Errorf("invalid object header")
+	}
+	return typ, data, nil
+}
+
+// A gitPack is a packfile together with its index.
+type gitPack struct {
+	file    string
+	count   int
+	hashes  []byte
+	offsets []byte
+	large   []byte
+}
+
+// readPackIndex reads a version 2 pack index.
+func readPackIndex(file string) (*gitPack, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	const header = 8 + 256*4
+	if len(data) < header || !bytes.Equal(data[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(data[4:]) != 2 {
+		return nil, fmt.Errorf("unsupported pack index: %s", file)
+	}
+	n := int(binary.BigEndian.Uint32(data[header-4:]))
+	if len(data) < header+28*n {
+		return nil, fmt.Errorf("truncated pack index: %s", file)
+	}
+	return &gitPack{
+		file:    strings.TrimSuffix(file, ".idx") + ".pack",
+		count:   n,
+		hashes:  data[header : header+20*n],
+		offsets: data[header+24*n : header+28*n],
+		large:   data[header+28*n:],
+	}, nil
+}
+
+func (p *gitPack) hash(i int) []byte {
+	return p.hashes[20*i : 20*i+20]
+}
+
+// offset returns the position of an object within the packfile.
+func (p *gitPack) offset(hash []byte) (int64, bool) {
+	i := sort.Search(p.count, func(i int) bool {
+		return bytes.Compare(p.hash(i), hash) >= 0
+	})
+	if i == p.count || !bytes.Equal(p.hash(i), hash) {
+		return 0, false
+	}
+	offset := binary.BigEndian.Uint32(p.offsets[4*i:])
+	if offset&0x80000000 == 0 {
+		return int64(offset), true
+	}
+	i = int(offset & 0x7fffffff)
+	if len(p.large) < 8*i+8 {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(p.large[8*i:])), true
+}
+
+var packObjectTypes = map[byte]string{1: "commit", 2: "tree", 3: "blob", 4: "tag"}
+
+const (
+	packOfsDelta = 6
+	packRefDelta = 7
+)
+
+// maxDeltaDepth is the maximum length of a chain of deltas, which git limits
+// to 4095, so that deltas of corrupt packs referring to themselves fail.
+const maxDeltaDepth = 4095
+
+// readPackObject reads the object at offset of a packfile, resolving
+// deltas. depth is the number of deltas which are based on the object.
+func (r *GitRepo) readPackObject(p *gitPack, offset int64, depth int) (string, []byte, error) {
+	if depth > maxDeltaDepth {
+		return "", nil, fmt.Errorf("delta chain too long at %d in %s", offset, p.file)
+	}
+	f, err := os.Open(p.file)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(io.NewSectionReader(f, offset, 1<<62))
+
+	c, err := br.ReadByte()
+	if err != nil {
+		return "", nil, err
+	}
+	typ := (c >> 4) & 7
+	for c&0x80 != 0 {
+		// the size is not needed, the zlib stream ends by itself.
+		c, err = br.ReadByte()
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	var baseType string
+	var base []byte
+	switch typ {
+	case packOfsDelta:
+		c, err = br.ReadByte()
+		rel := int64(c & 0x7f)
+		for err == nil && c&0x80 != 0 {
+			c, err = br.ReadByte()
+			rel = (rel+1)<<7 | int64(c&0x7f)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		// the base precedes the delta, which also rules out cycles in
+		// corrupt packs.
+		if rel <= 0 || rel > offset {
+			return "", nil, fmt.Errorf("invalid delta base offset at %d in %s", offset, p.file)
+		}
+		baseType, base, err = r.readPackObject(p, offset-rel, depth+1)
+	case packRefDelta:
+		hash := make([]byte, 20)
+		_, err = io.ReadFull(br, hash)
+		if err != nil {
+			return "", nil, err
+		}
+		baseType, base, err = r.readObject(hex.EncodeToString(hash), depth+1)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	zr, err := zlib.NewReader(br)
+	if err != nil {
+		return "", nil, err
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if base == nil {
+		name, ok := packObjectTypes[typ]
+		if !ok {
+			return "", nil, fmt.Errorf("invalid packed object type %d in %s", typ, p.file)
+		}
+		return name, data, nil
+	}
+	data, err = applyDelta(base, data)
+	return baseType, data, err
+}
+
+// applyDelta reconstructs an object from its base and a delta, which
+// consists of copy and insert instructions.
+func applyDelta(base, delta []byte) ([]byte, error) {
+	errInvalid := fmt.Errorf("invalid delta")
+	varint := func() int {
+		n, shift := 0, 0
+		for len(delta) > 0 {
+			c := delta[0]
+			delta = delta[1:]
+			n |= int(c&0x7f) << shift
+			shift += 7
+			if c&0x80 == 0 {
+				break
+			}
+		}
+		return n
+	}
+	if varint() != len(base) {
+		return nil, errInvalid
+	}
+	// every instruction takes at least one byte of the delta, and inserts
+	// at most 0x7f bytes or copies at most the whole base.
+	size := varint()
+	if size < 0 || size/max(len(base), 0x7f) > len(delta) {
+		return nil, errInvalid
+	}
+	out := make([]byte, 0, size)
+
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		switch {
+		case op&0x80 != 0:
+			var offset, size int
+			for i := 0; i < 7; i++ {
+				if op&(1<<i) == 0 {
+					continue
+				}
+				if len(delta) == 0 {
+					return nil, errInvalid
+				}
+				if i < 4 {
+					offset |= int(delta[0]) << (8 * i)
+				} else {
+					size |= int(delta[0]) << (8 * (i - 4))
+				}
+				delta = delta[1:]
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if offset+size > len(base) {
+				return nil, errInvalid
+			}
+			out = append(out, base[offset:offset+size]...)
+		case op != 0:
+			if int(op) > len(delta) {
+				return nil, errInvalid
+			}
+			out = append(out, delta[:op]...)
+			delta = delta[op:]
+		default:
+			return nil, errInvalid
+		}
+	}
+	if len(out) != cap(out) {
+		return nil, errInvalid
+	}
+	return out, nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// A gitTreeEntry is a file or directory of a tree object.
+type gitTreeEntry struct {
+	name string
+	mode uint32
+	hash string
+}
+
+func (e gitTreeEntry) isDir() bool {
+	return e.mode == 040000
+}
+
+// tree returns the entries of a tree object. Trees are cached, since the
+// same directories are read again and again when walking the history.
+func (r *GitRepo) tree(hash string) ([]gitTreeEntry, error) {
+	r.mu.Lock()
+	entries, ok := r.trees[hash]
+	r.mu.Unlock()
+	if ok {
+		return entries, nil
+	}
+
+	typ, data, err := r.object(hash)
+	if err != nil {
+		return nil, err
+	}
+	if typ != "tree" {
+		return nil, fmt.Errorf("not a tree: %s", hash)
+	}
+	entries = make([]gitTreeEntry, 0)
+	for len(data) > 0 {
+		header, rest, ok := bytes.Cut(data, []byte{0})
+		mode, name, _ := strings.Cut(string(header), " ")
+		m, err := strconv.ParseUint(mode, 8, 32)
+		if !ok || err != nil || len(rest) < 20 {
+			return nil, fmt.Errorf("invalid tree: %s", hash)
+		}
+		entries = append(entries, gitTreeEntry{name: name, mode: uint32(m), hash: hex.EncodeToString(rest[:20])})
+		data = rest[20:]
+	}
+
+	r.mu.Lock()
+	r.trees[hash] = entries
+	r.mu.Unlock()
+	return entries, nil
+}
+
+// lookup returns the entry located at the slash separated path within a
+// tree.
+func (r *GitRepo) lookup(tree, name string) (gitTreeEntry, error) {
+	e := gitTreeEntry{name: ".", mode: 040000, hash: tree}
+	if name == "." {
+		return e, nil
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if !e.isDir() {
+			return e, fs.ErrNotExist
+		}
+		entries, err := r.tree(e.hash)
+		if err != nil {
+			return e, err
+		}
+		// git sorts directories as if their names ended with a slash,
+		// so the entries can not be searched by name.
+		found := false
+		for _, entry := range entries {
+			if entry.name == elem {
+				e, found = entry, true
+				break
+			}
+		}
+		if !found {
+			return e, fs.ErrNotExist
+		}
+	}
+	return e, nil
+}
+
+// gitFS is the file system of a tree object. Symbolic links and submodules
+// are not part of it.
+type gitFS struct {
+	repo *GitRepo
+	tree string
+}
+
+func (fsys gitFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	e, err := fsys.repo.lookup(fsys.tree, name)
+	if err == nil && !e.isDir() && !isRegular(e.mode) {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	e.name = path.Base(name)
+
+	if e.isDir() {
+		entries, err := fsys.repo.tree(e.hash)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		dir := &gitDir{info: gitFileInfo{entry: e}}
+		for _, entry := range entries {
+			if entry.isDir() || isRegular(entry.mode) {
+				dir.entries = append(dir.entries, gitFileInfo{entry: entry, repo: fsys.repo})
+			}
+		}
+		sort.Slice(dir.entries, func(i, j int) bool { return dir.entries[i].entry.name < dir.entries[j].entry.name })
+		return dir, nil
+	}
+
+	_, data, err := fsys.repo.object(e.hash)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	size := int64(len(data))
+	return &gitFile{Reader: bytes.NewReader(data), info: gitFileInfo{entry: e, size: &size}}, nil
+}
+
+func isRegular(mode uint32) bool {
+	return mode&0170000 == 0100000
+}
+
+// gitFileInfo describes a file or directory of a gitFS. The size of files is
+// only read from the repository when needed.
+type gitFileInfo struct {
+	entry gitTreeEntry
+	repo  *GitRepo
+	size  *int64
+}
+
+func (i gitFileInfo) Name() string { return i.entry.name }
+
+func (i gitFileInfo) Size() int64 {
+	if i.size != nil {
+		return *i.size
+	}
+	if i.entry.isDir() || i.repo == nil {
+		return 0
+	}
+	_, data, _ := i.repo.object(i.entry.hash)
+	return int64(len(data))
+}
+
+func (i gitFileInfo) Mode() fs.FileMode {
+	if i.entry.isDir() {
+		return fs.ModeDir | 0555
+	}
+	if i.entry.mode&0111 != 0 {
+		return 0555
+	}
+	return 0444
+}
+
+func (i gitFileInfo) ModTime() time.Time         { return time.Time{} }
+func (i gitFileInfo) IsDir() bool                { return i.entry.isDir() }
+func (i gitFileInfo) Sys() interface{}           { return nil }
+func (i gitFileInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i gitFileInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+type gitFile struct {
+	*bytes.Reader
+	info gitFileInfo
+}
+
+func (f *gitFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *gitFile) Close() error               { return nil }
+
+type gitDir struct {
+	info    gitFileInfo
+	entries []gitFileInfo
+	offset  int
+}
+
+func (d *gitDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *gitDir) Close() error               { return nil }
+
+func (d *gitDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+func (d *gitDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n > 0 && len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && n < len(rest) {
+		rest = rest[:n]
+	}
+	d.offset += len(rest)
+
+	entries := make([]fs.DirEntry, len(rest))
+	for i, e := range rest {
+		entries[i] = e
+	}
+	return entries, nil
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitRepo creates a repository with two commits of a project located in
+// arch: the first one, tagged v1, contains test/ok, the second one adds a
+// system.
+func gitRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=a", "GIT_AUTHOR_EMAIL=a@example.com",
+			"GIT_COMMITTER_NAME=a", "GIT_COMMITTER_EMAIL=a@example.com", "GIT_CONFIG_NOSYSTEM=1", "HOME="+dir)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	err := os.MkdirAll(filepath.Join(dir, "arch"), 0755)
+	assertEqual(t, nil, err, "MkdirAll returned an error")
+	for _, name := range []string{"component.c4", "container.c4", "sys.c4"} {
+		content, err := os.ReadFile(filepath.Join("test", "ok", name))
+		assertEqual(t, nil, err, "ReadFile returned an error")
+		err = os.WriteFile(filepath.Join(dir, "arch", name), content, 0644)
+		assertEqual(t, nil, err, "WriteFile returned an error")
+	}
+	git("init", "-q", "-b", "main")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial architecture")
+	git("tag", "-a", "v1", "-m", "version 1")
+
+	f, err := os.OpenFile(filepath.Join(dir, "arch", "sys.c4"), os.O_APPEND|os.O_WRONLY, 0644)
+	assertEqual(t, nil, err, "OpenFile returned an error")
+	_, err = f.WriteString("System = Newsletter | Sends weekly mails |\n")
+	assertEqual(t, nil, err, "WriteString returned an error")
+	f.Close()
+	git("commit", "-q", "-a", "-m", "add newsletter")
+	return dir
+}
+
+func TestParseRevision(t *testing.T) {
+	dir := gitRepo(t)
+	project := filepath.Join(dir, "arch")
+
+	expected, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+	for _, rev := range []string{"v1", "main~1", "HEAD^"} {
+		m, err := ParseRevision(project, rev)
+		assertEqual(t, nil, err, "ParseRevision returned an error")
+		assertEqual(t, expected.Systems, m.Systems, rev+": systems do not match")
+		assertEqual(t, expected.Relationships, m.Relationships, rev+": relationships do not match")
+	}
+
+	m, err := ParseRevision(project, "main")
+	assertEqual(t, nil, err, "ParseRevision returned an error")
+	_, ok := m.Systems["Newsletter"]
+	assertEqual(t, true, ok, "system of latest revision expected")
+
+	_, err = ParseRevision(project, "unknown")
+	assertEqual(t, "unknown revision: unknown", err.Error(), "unknown revision must fail")
+}
+
+func TestParseRevisionPacked(t *testing.T) {
+	dir := gitRepo(t)
+	cmd := exec.Command("git", "gc", "-q", "--aggressive")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git gc: %v\n%s", err, out)
+	}
+	project := filepath.Join(dir, "arch")
+
+	r, err := OpenGitRepo(project)
+	assertEqual(t, nil, err, "OpenGitRepo returned an error")
+	assertEqual(t, true, len(r.packs) > 0, "objects must be packed")
+	head, err := r.ResolveRevision("HEAD")
+	assertEqual(t, nil, err, "ResolveRevision returned an error")
+	abbrev, err := r.ResolveRevision(head[:7])
+	assertEqual(t, nil, err, "ResolveRevision returned an error")
+	assertEqual(t, head, abbrev, "abbreviated hash must be resolved")
+
+	commits, err := r.Log(head)
+	assertEqual(t, nil, err, "Log returned an error")
+	assertEqual(t, 2, len(commits), "log must contain all commits")
+	assertEqual(t, "add newsletter", commits[0].Subject(), "subject does not match")
+
+	m, err := ParseRevision(project, "v1")
+	assertEqual(t, nil, err, "ParseRevision returned an error")
+	assertEqual(t, []error{}, m.Errors, "model must not contain errors")
+
+	m, err = ParseRevision(project, "main")
+	assertEqual(t, nil, err, "ParseRevision returned an error")
+	assertEqual(t, []error{}, m.Errors, "model must not contain errors")
+	assertEqual(t, 1, len(m.Warnings), "model must contain a warning")
+	assertEqual(t, "main:arch/sys.c4:19: warning: System is not used in any Relationship: Newsletter", m.Warnings[0].Error(),
+		"warnings must refer to the revision")
+}
+
+func TestApplyDelta(t *testing.T) {
+	base := []byte("hello world")
+	// copy "hello " from the base, insert "blueprint".
+	delta := []byte{11, 15, 0x90, 6, 9, 'b', 'l', 'u', 'e', 'p', 'r', 'i', 'n', 't'}
+	out, err := applyDelta(base, delta)
+	assertEqual(t, nil, err, "applyDelta returned an error")
+	assertEqual(t, "hello blueprint", string(out), "result does not match")
+
+	_, err = applyDelta([]byte("short"), delta)
+	assertEqual(t, "invalid delta", err.Error(), "delta of a different base must fail")
+	_, err = applyDelta(base, []byte{11, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f})
+	assertEqual(t, "invalid delta", err.Error(), "delta of a huge size must fail")
+}
+
+func TestReadPackObjectInvalidBase(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pack")
+	// an OFS_DELTA of size 1 at offset 12 referring to itself, and one
+	// referring to a base before the start of the pack.
+	pack := append([]byte("PACK\x00\x00\x00\x02\x00\x00\x00\x02"), 0x61, 0x00, 0x61, 0x7f)
+	err := os.WriteFile(file, pack, 0644)
+	assertEqual(t, nil, err, "WriteFile returned an error")
+
+	r := &GitRepo{}
+	p := &gitPack{file: file}
+	_, _, err = r.readPackObject(p, 12, 0)
+	assertEqual(t, "invalid delta base offset at 12 in "+file, err.Error(), "delta referring to itself must fail")
+	_, _, err = r.readPackObject(p, 14, 0)
+	assertEqual(t, "invalid delta base offset at 14 in "+file, err.Error(), "delta referring before the pack must fail")
+}
+
+func TestReadPackObjectRefDeltaCycle(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pack")
+	// a REF_DELTA of size 1 at offset 12 referring to its own hash.
+	hash := bytes.Repeat([]byte{0xab}, 20)
+	pack := append([]byte("PACK\x00\x00\x00\x02\x00\x00\x00\x01\x71"), hash...)
+	err := os.WriteFile(file, pack, 0644)
+	assertEqual(t, nil, err, "WriteFile returned an error")
+
+	p := &gitPack{file: file, count: 1, hashes: hash, offsets: []byte{0, 0, 0, 12}}
+	r := &GitRepo{commonDir: t.TempDir(), packs: []*gitPack{p}}
+	_, _, err = r.object(hex.EncodeToString(hash))
+	assertEqual(t, "delta chain too long at 12 in "+file, err.Error(), "delta referring to itself must fail")
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// goTechnologies maps import paths to the technology they indicate. Imports
+// of a package match a path if they are equal to it or located below it.
+var goTechnologies = []struct {
+	path       string
+	technology string
+}{
+	{"net/http", "HTTP"},
+	{"github.com/gorilla/mux", "HTTP"},
+	{"github.com/go-chi/chi", "HTTP"},
+	{"github.com/gin-gonic/gin", "HTTP"},
+	{"github.com/labstack/echo", "HTTP"},
+	{"google.golang.org/grpc", "gRPC"},
+	{"database/sql", "SQL"},
+	{"github.com/jackc/pgx", "PostgreSQL"},
+	{"github.com/lib/pq", "PostgreSQL"},
+	{"github.com/go-sql-driver/mysql", "MySQL"},
+	{"github.com/redis/go-redis", "Redis"},
+	{"github.com/go-redis/redis", "Redis"},
+	{"go.mongodb.org/mongo-driver", "MongoDB"},
+	{"github.com/segmentio/kafka-go", "Kafka"},
+	{"github.com/IBM/sarama", "Kafka"},
+	{"github.com/Shopify/sarama", "Kafka"},
+	{"github.com/nats-io/nats.go", "NATS"},
+}
+
+// A GoGroup assigns the Go packages matching Pattern, see GoPackage, to the
+// component Name. The packages are excluded if Name is empty.
+type GoGroup struct {
+	Pattern string
+	Name    string
+}
+
+// ParseGoGroup parses a group of the form Pattern=Name.
+func ParseGoGroup(s string) (GoGroup, error) {
+	i := strings.LastIndex(s, "=")
+	if i == -1 {
+		return GoGroup{}, fmt.Errorf("group requires the form Pattern=Name: %s", s)
+	}
+	return GoGroup{Pattern: strings.TrimSpace(s[:i]), Name: strings.TrimSpace(s[i+1:])}, nil
+}
+
+// goComponentDraft collects the packages of a component to be extracted.
+type goComponentDraft struct {
+	name     string
+	patterns map[string]bool
+	pkgs     []goPackage
+}
+
+// ExtractGo generates project source defining the components of a container
+// from the Go packages matching patterns, like VerifyGo. Packages are
+// grouped into components by the first matching group, or, by default, by
+// their top level directory within the module, where internal, pkg and cmd
+// are skipped, e.g. internal/cart/store belongs to the component Cart.
+//
+// Descriptions are taken from the package documentation and technologies
+// from the imports, e.g. HTTP for net/http. Every import between the
+// packages of two components results in a Relationship, and every component
+// is mapped to its packages by GoPackage, so that the result can be checked
+// with VerifyGo after refining it. If system is not empty, the container is
+// defined as well.
+func ExtractGo(dir, system, container string, groups []GoGroup, patterns ...string) ([]byte, error) {
+	_, modPath, err := goModule(dir)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := loadGoPackages(dir, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	drafts := make(map[string]*goComponentDraft)
+	components := make(map[string]string)
+	for _, pkg := range pkgs {
+		name, pattern := goGroup(modPath, pkg, groups)
+		if name == "" {
+			continue
+		}
+		d, ok := drafts[name]
+		if !ok {
+			d = &goComponentDraft{name: name, patterns: make(map[string]bool)}
+			drafts[name] = d
+		}
+		d.patterns[pattern] = true
+		d.pkgs = append(d.pkgs, pkg)
+		components[pkg.Path] = name
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by blueprint extract-go from %s.\n", modPath)
+	if system != "" {
+		fmt.Fprintf(&b, "\nContainer = %s | %s | | Go |\n", system, container)
+	}
+
+	b.WriteString("\n")
+	for _, name := range sortedKeys(drafts) {
+		d := drafts[name]
+		fmt.Fprintf(&b, "Component = %s | %s | %s | %s |\n", container, name, goDescription(d.pkgs), goTechnology(d.pkgs))
+	}
+
+	uses := make(map[string]map[string]bool)
+	for _, pkg := range pkgs {
+		src := components[pkg.Path]
+		for _, imp := range pkg.Imports {
+			dst := components[imp]
+			if src == "" || dst == "" || src == dst {
+				continue
+			}
+			if uses[src] == nil {
+				uses[src] = make(map[string]bool)
+			}
+			uses[src][dst] = true
+		}
+	}
+	if len(uses) > 0 {
+		b.WriteString("\n")
+	}
+	for _, src := range sortedKeys(uses) {
+		for _, dst := range sortedKeys(uses[src]) {
+			fmt.Fprintf(&b, "Relationship = %s | Uses | Go | %s |\n", src, dst)
+		}
+	}
+
+	b.WriteString("\n")
+	for _, name := range sortedKeys(drafts) {
+		fmt.Fprintf(&b, "GoPackage = %s | %s\n", name, strings.Join(sortedKeys(drafts[name].patterns), ", "))
+	}
+	return Format([]byte(b.String())), nil
+}
+
+// goGroup returns the component of a package together with the pattern
+// selecting it.
+func goGroup(modPath string, pkg goPackage, groups []GoGroup) (string, string) {
+	for _, g := range groups {
+		if matchGoPattern(g.Pattern, pkg.Path) {
+			return g.Name, g.Pattern
+		}
+	}
+	if pkg.Dir == "" {
+		return title(path.Base(modPath)), pkg.Path
+	}
+	parts := strings.Split(pkg.Dir, "/")
+	n := 1
+	if len(parts) > 1 && (parts[0] == "internal" || parts[0] == "pkg" || parts[0] == "cmd") {
+		n = 2
+	}
+	return title(parts[n-1]), modPath + "/" + strings.Join(parts[:n], "/") + "/..."
+}
+
+// goDescription returns the documentation of the top most documented
+// package, without the leading "Package name".
+func goDescription(pkgs []goPackage) string {
+	sorted := append([]goPackage(nil), pkgs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i].Path) < len(sorted[j].Path) })
+	for _, pkg := range sorted {
+		if pkg.Doc == "" {
+			continue
+		}
+		doc := strings.TrimPrefix(pkg.Doc, "Package "+pkg.Name+" ")
+		// the separators of the syntax must not be part of fields.
+		doc = strings.NewReplacer("|", "/", "\\", "/").Replace(doc)
+		return title(doc)
+	}
+	return ""
+}
+
+// goTechnology returns Go, followed by the technologies indicated by the
+// imports of the packages.
+func goTechnology(pkgs []goPackage) string {
+	techs := []string{"Go"}
+	for _, t := range goTechnologies {
+		for _, pkg := range pkgs {
+			for _, imp := range pkg.Imports {
+				if (imp == t.path || strings.HasPrefix(imp, t.path+"/")) && !contains(techs, t.technology) {
+					techs = append(techs, t.technology)
+				}
+			}
+		}
+	}
+	return strings.Join(techs, ", ")
+}
+
+// title returns s with its first letter in upper case.
+func title(s string) string {
+	if s == "" {
+		return s
+	}
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[n:]
+}
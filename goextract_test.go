@@ -0,0 +1,74 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"testing"
+)
+
+func TestExtractGo(t *testing.T) {
+	dir := goModuleDir(t)
+
+	src, err := ExtractGo(dir, "Shop", "App", nil, "./...")
+	assertEqual(t, nil, err, "ExtractGo returned an error")
+	assertEqual(t, `# Generated by blueprint extract-go from example.com/shop.
+
+Container = Shop | App | | Go |
+
+Component = App | Cart   |                 | Go       |
+Component = App | Db     |                 | Go       |
+Component = App | Orders | Manages orders. | Go, HTTP |
+Component = App | Shop   |                 | Go       |
+Component = App | Tax    |                 | Go, SQL  |
+
+Relationship = Cart   | Uses | Go | Orders |
+Relationship = Orders | Uses | Go | Db     |
+Relationship = Shop   | Uses | Go | Cart   |
+
+GoPackage = Cart   | example.com/shop/cart/...
+GoPackage = Db     | example.com/shop/db/...
+GoPackage = Orders | example.com/shop/orders/...
+GoPackage = Shop   | example.com/shop/cmd/shop/...
+GoPackage = Tax    | example.com/shop/internal/tax/...
+`, string(src), "source does not match")
+
+	// the extracted model must conform to the packages.
+	m := parseString(t, "System = Shop | |\n"+string(src))
+	errs, err := VerifyGo(m, dir, "./...")
+	assertEqual(t, nil, err, "VerifyGo returned an error")
+	assertEqual(t, []error{}, errs, "extracted model must conform")
+}
+
+func TestExtractGoGroups(t *testing.T) {
+	dir := goModuleDir(t)
+	groups := make([]GoGroup, 0)
+	for _, s := range []string{"example.com/shop/cmd/...=", "example.com/shop/db=Database", "example.com/shop/...=Core"} {
+		g, err := ParseGoGroup(s)
+		assertEqual(t, nil, err, "ParseGoGroup returned an error")
+		groups = append(groups, g)
+	}
+
+	src, err := ExtractGo(dir, "", "App", groups, "./...")
+	assertEqual(t, nil, err, "ExtractGo returned an error")
+	assertEqual(t, `# Generated by blueprint extract-go from example.com/shop.
+
+Component = App | Core     | Manages orders. | Go, HTTP, SQL |
+Component = App | Database |                 | Go            |
+
+Relationship = Core | Uses | Go | Database |
+
+GoPackage = Core     | example.com/shop/...
+GoPackage = Database | example.com/shop/db
+`, string(src), "source does not match")
+
+	m := parseString(t, "System = Shop | |\nContainer = Shop | App | | |\n"+string(src))
+	errs, err := VerifyGo(m, dir, "./...")
+	assertEqual(t, nil, err, "VerifyGo returned an error")
+	assertEqual(t, []error{}, errs, "extracted model must conform")
+
+	_, err = ParseGoGroup("example.com/shop")
+	assertEqual(t, "group requires the form Pattern=Name: example.com/shop", err.Error(), "group without name must fail")
+}
@@ -0,0 +1,373 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/build"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+func parseGoPackage(m *Model, path string, lineno int, value string) {
+	fields := strings.Split(value, "|")
+	if len(fields) < 2 {
+		m.addErr(path, lineno, "GoPackage requires 2 elements: Component | Package Patterns")
+		return
+	}
+	if len(fields) > 2 {
+		m.addErr(path, lineno, "GoPackage requires 2 elements: Component | Package Patterns")
+	}
+
+	component := strings.TrimSpace(fields[0])
+	patterns := nonEmpty(parseTags(fields[1]))
+	if len(patterns) == 0 {
+		m.addErr(path, lineno, "GoPackage requires at least 1 package pattern")
+		return
+	}
+
+	if _, ok := m.GoPackages[component]; ok {
+		m.addErr(path, lineno, "GoPackage is already defined for Component: "+component)
+		return
+	}
+	m.define("GoPackage", component, path, lineno)
+	m.GoPackages[component] = GoPackage{Component: component, Patterns: patterns}
+}
+
+// validateGoPackages checks the components referenced by GoPackages.
+func (m *Model) validateGoPackages() {
+	for _, name := range sortedKeys(m.GoPackages) {
+		if _, ok := m.Components[name]; !ok {
+			pos := m.positions["GoPackage:"+name]
+			m.addErr(pos.File, pos.Line, "Component of GoPackage is not defined: "+name)
+		}
+	}
+}
+
+// matchGoPattern reports whether a package path matches a pattern, where
+// ... matches any string, like in patterns of the go command. As a special
+// case, a trailing /... also matches the package path before it, e.g.
+// net/... matches net.
+func matchGoPattern(pattern, pkg string) bool {
+	re, ok := goPatterns.Load(pattern)
+	if !ok {
+		expr := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\.\.\.`, `.*`)
+		if strings.HasSuffix(expr, `/.*`) {
+			expr = strings.TrimSuffix(expr, `/.*`) + `(/.*)?`
+		}
+		re, _ = goPatterns.LoadOrStore(pattern, regexp.MustCompile("^"+expr+"$"))
+	}
+	return re.(*regexp.Regexp).MatchString(pkg)
+}
+
+// goPatterns caches the regular expressions of the patterns matched by
+// matchGoPattern, which is called for every import of every package.
+var goPatterns sync.Map
+
+// goComponent returns the component a Go package is mapped to by
+// GoPackages, or "" if there is none. If patterns of several components
+// match, the most specific one wins: the one with the longest path before
+// the first ..., or without any.
+func (m Model) goComponent(pkg string) string {
+	component, best := "", -1
+	for _, name := range sortedKeys(m.GoPackages) {
+		for _, pattern := range m.GoPackages[name].Patterns {
+			specificity := strings.Index(pattern, "...")
+			if specificity == -1 {
+				specificity = len(pattern) + 1
+			}
+			if specificity > best && matchGoPattern(pattern, pkg) {
+				component, best = name, specificity
+			}
+		}
+	}
+	return component
+}
+
+// enclosing returns the set of the element and all elements containing it:
+// the container and system of a component, or the system of a container.
+func (m Model) enclosing(name string) map[string]bool {
+	set := make(map[string]bool)
+	for name != "" && !set[name] {
+		set[name] = true
+		if c, ok := m.Components[name]; ok {
+			name = c.Container
+		} else if c, ok := m.Containers[name]; ok {
+			name = c.System
+		} else {
+			name = ""
+		}
+	}
+	return set
+}
+
+// A goPackage is a Go package of a module together with its imports.
+type goPackage struct {
+	Path    string
+	Name    string
+	Imports []string
+	// Dir is the directory of the package relative to the module root,
+	// Doc the synopsis of its documentation.
+	Dir string
+	Doc string
+	// pos contains the position of the first import of each package.
+	pos map[string]position
+}
+
+// goModule returns the root directory and the module path of the Go module
+// containing dir.
+func goModule(dir string) (string, string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", err
+	}
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			path := modulePath(data)
+			if path == "" {
+				return "", "", fmt.Errorf("%s: missing module declaration", filepath.Join(dir, "go.mod"))
+			}
+			return dir, path, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", errors.New("go.mod not found")
+		}
+		dir = parent
+	}
+}
+
+// modulePath returns the path of the module declaration of a go.mod file.
+func modulePath(mod []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(mod))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		rest, ok := strings.CutPrefix(line, "module")
+		if !ok || rest == "" || (rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		rest = strings.TrimSpace(rest)
+		if i := strings.Index(rest, "//"); i >= 0 {
+			rest = strings.TrimSpace(rest[:i])
+		}
+		if p, err := strconv.Unquote(rest); err == nil {
+			return p
+		}
+		return rest
+	}
+	return ""
+}
+
+// loadGoPackages reads the imports of the Go packages matching patterns,
+// which are directories relative to dir, optionally followed by /... to
+// include all packages below them. Test files are ignored, since they do not
+// contribute to the dependencies of a component, but files of all operating
+// systems and build tags are read, unless they belong to another package.
+func loadGoPackages(dir string, patterns []string) ([]goPackage, error) {
+	root, modPath, err := goModule(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make(map[string]bool)
+	for _, pattern := range patterns {
+		base, recursive := strings.CutSuffix(pattern, "...")
+		base = filepath.Join(dir, filepath.FromSlash(base))
+		if !recursive {
+			dirs[base] = true
+			continue
+		}
+		err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return err
+			}
+			name := d.Name()
+			if path != base && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+				name == "testdata" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			// directories containing a go.mod file belong to another module.
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil && path != root {
+				return filepath.SkipDir
+			}
+			dirs[path] = true
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the files of all operating systems, architectures and build tags
+	// contribute to the dependencies of a component, except for files
+	// excluded by the ignore tag, which usually contain separate programs.
+	ctxt := build.Default
+	ctxt.UseAllFiles = true
+	ctxt.ReadDir = func(dir string) ([]fs.FileInfo, error) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		infos := make([]fs.FileInfo, 0, len(entries))
+		for _, e := range entries {
+			if strings.HasSuffix(e.Name(), ".go") && ignoredGoFile(filepath.Join(dir, e.Name())) {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+		return infos, nil
+	}
+
+	pkgs := make([]goPackage, 0)
+	for _, d := range sortedKeys(dirs) {
+		bp, err := ctxt.ImportDir(d, 0)
+		var multiple *build.MultiplePackageError
+		if errors.As(err, &multiple) {
+			// files of other packages are left to the build constraints.
+			bp, err = build.ImportDir(d, 0)
+		}
+		var noGo *build.NoGoError
+		if errors.As(err, &noGo) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		abs, err := filepath.Abs(d)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return nil, fmt.Errorf("%s is not part of module %s", d, modPath)
+		}
+		pkg := goPackage{Path: modPath, Name: bp.Name, Imports: bp.Imports, Doc: bp.Doc, pos: make(map[string]position)}
+		if rel != "." {
+			pkg.Dir = filepath.ToSlash(rel)
+			pkg.Path += "/" + pkg.Dir
+		}
+		for _, imp := range bp.Imports {
+			if p := bp.ImportPos[imp]; len(p) > 0 {
+				file, _ := filepath.Rel(dir, p[0].Filename)
+				pkg.pos[imp] = position{File: file, Line: p[0].Line}
+			}
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+// ignoredGoFile reports whether the build constraint of a Go file contains
+// the ignore tag, e.g. //go:build ignore.
+func ignoredGoFile(file string) bool {
+	f, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+		if rest, ok := strings.CutPrefix(line, "//go:build "); ok {
+			tags := strings.FieldsFunc(rest, func(r rune) bool { return strings.ContainsRune(" \t!()&|", r) })
+			return contains(tags, "ignore")
+		}
+	}
+	return false
+}
+
+// VerifyGo compares the imports between the Go packages matching patterns,
+// e.g. ./..., with the relationships between the components they are mapped
+// to by GoPackages. The patterns are directories relative to dir, which must
+// be part of a Go module. The locations of imports are relative to dir, too.
+//
+// Imports between components without a relationship from the importing
+// component, or an element containing it, to the imported one, or an
+// element containing it, are reported as undocumented dependencies, located
+// at the import. Relationships between components which are both mapped to
+// packages, without any import between them, are reported as stale, located
+// at the relationship. Imports of packages not mapped to any component, e.g.
+// of the standard library, are ignored.
+func VerifyGo(m Model, dir string, patterns ...string) ([]error, error) {
+	pkgs, err := loadGoPackages(dir, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, 0)
+	loaded := make(map[string]bool)
+	imported := make(map[[2]string]bool)
+	for _, pkg := range pkgs {
+		src := m.goComponent(pkg.Path)
+		if src == "" {
+			continue
+		}
+		loaded[src] = true
+		for _, imp := range pkg.Imports {
+			dst := m.goComponent(imp)
+			if dst == "" || dst == src {
+				continue
+			}
+			imported[[2]string{src, dst}] = true
+			if !m.documented(src, dst) {
+				pos := pkg.pos[imp]
+				errs = append(errs, parseError{File: pos.File, Line: pos.Line,
+					Msg: "undocumented dependency: " + src + " -> " + dst + " (" + pkg.Path + " imports " + imp + ")"})
+			}
+		}
+	}
+
+	for i, r := range m.Relationships {
+		_, mapped := m.GoPackages[r.Destination]
+		if !loaded[r.Source] || !mapped || imported[[2]string{r.Source, r.Destination}] {
+			continue
+		}
+		pos := m.relPosition(i)
+		errs = append(errs, parseError{File: pos.File, Line: pos.Line,
+			Msg: "stale Relationship, no package of " + r.Source + " imports " + r.Destination + ": " +
+				r.Source + " -> " + r.Destination})
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		a, b := errs[i].(parseError), errs[j].(parseError)
+		return a.File < b.File || a.File == b.File && a.Line < b.Line
+	})
+	return errs, nil
+}
+
+// documented reports whether there is a relationship from the source, or an
+// element containing it, to the destination, or an element containing it.
+func (m Model) documented(source, destination string) bool {
+	sources, destinations := m.enclosing(source), m.enclosing(destination)
+	for _, r := range m.Relationships {
+		if sources[r.Source] && destinations[r.Destination] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+const goSource = `System = Shop | |
+Container = Shop | App | | |
+Container = Shop | Store | | |
+Component = App | Cart | | |
+Component = App | Orders | | |
+Component = Store | DB | | |
+Relationship = Cart | Stores carts | SQL | DB |
+Relationship = Orders | Stores orders | SQL | Store |
+GoPackage = Cart | example.com/shop/cart/...
+GoPackage = Orders | example.com/shop/orders, example.com/shop/internal/...
+GoPackage = DB | example.com/shop/db
+GoPackage = Payments | example.com/shop/payments
+`
+
+// goModuleDir creates a module, in which cart imports orders, orders imports
+// db, and the shop command imports cart.
+func goModuleDir(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":              "module example.com/shop // the shop\n\ngo 1.21\n",
+		"cart/cart.go":        "package cart\n\nimport (\n\t\"fmt\"\n\t\"example.com/shop/orders\"\n)\n\nvar _ = fmt.Sprint(orders.Name)\n",
+		"cart/cart_test.go":   "package cart\n\nimport _ \"example.com/shop/db\"\n",
+		"cart/items/items.go": "package items\n",
+		"orders/orders.go":    "// Package orders manages orders.\npackage orders\n\nimport (\n\t_ \"example.com/shop/db\"\n\t_ \"net/http\"\n)\n\nconst Name = \"orders\"\n",
+		"internal/tax/tax.go": "package tax\n\nimport _ \"database/sql\"\n",
+		"cmd/shop/main.go":    "package main\n\nimport _ \"example.com/shop/cart\"\n",
+		"db/db.go":            "package db\n",
+		"testdata/x/x.go":     "package x\n\nimport _ \"example.com/shop/cart\"\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		assertEqual(t, nil, err, "MkdirAll returned an error")
+		err = os.WriteFile(path, []byte(content), 0644)
+		assertEqual(t, nil, err, "WriteFile returned an error")
+	}
+	return dir
+}
+
+func TestParseGoPackages(t *testing.T) {
+	m := parseString(t, goSource)
+
+	assertEqual(t, GoPackage{Component: "Orders", Patterns: []string{"example.com/shop/orders", "example.com/shop/internal/..."}},
+		m.GoPackages["Orders"], "GoPackage does not match")
+	assertEqual(t, parseError{File: "test.c4", Line: 12, Msg: "Component of GoPackage is not defined: Payments"},
+		m.Errors[0], "undefined component must fail")
+}
+
+func TestMatchGoPattern(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, pkg string
+		match        bool
+	}{
+		{"example.com/shop/cart", "example.com/shop/cart", true},
+		{"example.com/shop/cart", "example.com/shop/cart/items", false},
+		{"example.com/shop/cart/...", "example.com/shop/cart", true},
+		{"example.com/shop/cart/...", "example.com/shop/cart/items", true},
+		{"example.com/shop/cart/...", "example.com/shop/cartography", false},
+		{"example.com/.../db", "example.com/shop/db", true},
+	} {
+		assertEqual(t, tc.match, matchGoPattern(tc.pattern, tc.pkg), tc.pattern+" "+tc.pkg)
+	}
+}
+
+func TestVerifyGo(t *testing.T) {
+	m := parseString(t, goSource)
+	dir := goModuleDir(t)
+
+	errs, err := VerifyGo(m, dir, "./...")
+	assertEqual(t, nil, err, "VerifyGo returned an error")
+	assertEqual(t, []error{
+		parseError{File: filepath.Join("cart", "cart.go"), Line: 5,
+			Msg: "undocumented dependency: Cart -> Orders (example.com/shop/cart imports example.com/shop/orders)"},
+		parseError{File: "test.c4", Line: 7, Msg: "stale Relationship, no package of Cart imports DB: Cart -> DB"},
+	}, errs, "errors do not match")
+
+	// without the packages of Cart, its relationships are not checked.
+	errs, err = VerifyGo(m, dir, "./orders", "./db")
+	assertEqual(t, nil, err, "VerifyGo returned an error")
+	assertEqual(t, []error{}, errs, "errors do not match")
+
+	_, err = VerifyGo(m, t.TempDir(), "./...")
+	assertEqual(t, "go.mod not found", err.Error(), "directory outside of a module must fail")
+}
+
+func TestLoadGoPackagesAllFiles(t *testing.T) {
+	dir := goModuleDir(t)
+	files := map[string]string{
+		"db/db_other.go": "//go:build !" + runtime.GOOS + "\n\npackage db\n\nimport _ \"example.com/shop/orders\"\n",
+		"db/gen.go":      "//go:build ignore\n\npackage main\n\nimport _ \"example.com/shop/cart\"\n",
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(content), 0644)
+		assertEqual(t, nil, err, "WriteFile returned an error")
+	}
+
+	pkgs, err := loadGoPackages(dir, []string{"./db"})
+	assertEqual(t, nil, err, "loadGoPackages returned an error")
+	assertEqual(t, 1, len(pkgs), "1 package expected")
+	assertEqual(t, "db", pkgs[0].Name, "package name does not match")
+	assertEqual(t, []string{"example.com/shop/orders"}, pkgs[0].Imports, "imports of other platforms are missing")
+}
@@ -10,15 +10,25 @@ import (
 	"fmt"
 	"io"
 	"os/exec"
+	"strconv"
 	"strings"
 	"text/template"
 )
 
 const dotTemplate = `
 digraph "{{.Title}}" {
-	ranksep="1.0";
-	nodesep="1.0";
-	node[fontcolor="white" fontsize=11 fontname="Sans" shape="box" style="filled,rounded" margin="0.20,0.20"];
+	ranksep="{{.LayoutOption "ranksep"}}";
+	nodesep="{{.LayoutOption "nodesep"}}";
+	{{- with .Layout.rankdir}}
+	rankdir="{{.}}";
+	{{- end}}
+	{{- with .Layout.splines}}
+	splines="{{.}}";
+	{{- end}}
+	{{- with .NodeSize}}
+	node[fontcolor="white" fontsize=11 fontname="Sans" shape="box" style="filled,rounded" margin="{{.Margin}}"
+		{{- with .Width}} width="{{.}}"{{end}}{{with .Height}} height="{{.}}"{{end}}];
+	{{- end}}
 	edge[fontcolor="dimgrey" color="dimgrey" fontsize=11 fontname="Sans"];
 
 	subgraph cluster_core {
@@ -27,6 +37,9 @@ digraph "{{.Title}}" {
 		{{- range .CoreNodes}}
 		"{{.Name}}" [{{range $k, $v := .Attrs}} {{$k}}=<{{$v}}>{{end}} ];
 		{{- end}}
+		{{- range $.ClusterRankHints "core"}}
+		{ rank="{{.Rank}}";{{range .Nodes}} "{{.}}";{{end}} }
+		{{- end}}
 	}
 
 	subgraph cluster_top {
@@ -35,6 +48,9 @@ digraph "{{.Title}}" {
 		{{- range .TopNodes}}
 		"{{.Name}}" [{{range $k, $v := .Attrs}} {{$k}}=<{{$v}}>{{end}} ];
 		{{- end}}
+		{{- range $.ClusterRankHints "top"}}
+		{ rank="{{.Rank}}";{{range .Nodes}} "{{.}}";{{end}} }
+		{{- end}}
 	}
 
 	subgraph cluster_bottom {
@@ -43,36 +59,164 @@ digraph "{{.Title}}" {
 		{{- range .BottomNodes}}
 		"{{.Name}}" [{{range $k, $v := .Attrs}} {{$k}}=<{{$v}}>{{end}} ];
 		{{- end}}
+		{{- range $.ClusterRankHints "bottom"}}
+		{ rank="{{.Rank}}";{{range .Nodes}} "{{.}}";{{end}} }
+		{{- end}}
 	}
 
+	{{- if .Legend}}
+
+	subgraph cluster_legend {
+		label="Legend";
+		fontcolor="dimgrey";
+		color="#dddddd";
+		style="rounded";
+		"blueprint:legend" [shape="plaintext" style="solid" fontcolor="black" margin="0" label=<{{.LegendLabel}}>];
+	}
+	{{- end}}
+
 	// relationships
 	{{- range .Edges}}
 	"{{.Source}}" -> "{{.Destination}}" [{{range $k, $v := .Attrs}} {{$k}}=<{{$v}}>{{end}} ];
 	{{- end}}
+
+	{{- if or .HintEdges (.ClusterRankHints "")}}
+
+	// layout hints
+	{{- range .ClusterRankHints ""}}
+	{ rank="{{.Rank}}";{{range .Nodes}} "{{.}}";{{end}} }
+	{{- end}}
+	{{- range .HintEdges}}
+	"{{.Source}}" -> "{{.Destination}}" [ style="invis" class="layout-hint" ];
+	{{- end}}
+	{{- end}}
 }
 `
 
 type graph struct {
-	Title       string
+	Title string
+	// Boundary describes the elements within the dashed core cluster.
+	Boundary    string
 	CoreNodes   []node
 	TopNodes    []node
 	BottomNodes []node
 	Edges       []edge
+	Legend      []legendEntry
+	// HintEdges are invisible edges, which only affect the ranking of
+	// nodes. RankHints put nodes on the same, the minimum or the maximum
+	// rank.
+	HintEdges []edge
+	RankHints []rankHint
+	// Layout contains the layout options of the view, see layoutOptions.
+	Layout map[string]string
 }
 
 type node struct {
-	Name  string
+	Name string
+	// Kind is the kind of the element shown by the node, e.g. System.
+	Kind  string
 	Attrs map[string]string
 }
 
+// A rankHint constrains the rank of nodes within the given cluster: core,
+// top, bottom, or the whole graph if empty. Rank is same, min or max.
+type rankHint struct {
+	Cluster string
+	Rank    string
+	Nodes   []string
+}
+
+// ClusterRankHints returns the rank hints of a cluster.
+func (g graph) ClusterRankHints(cluster string) []rankHint {
+	hints := make([]rankHint, 0)
+	for _, h := range g.RankHints {
+		if h.Cluster == cluster {
+			hints = append(hints, h)
+		}
+	}
+	return hints
+}
+
 type edge struct {
 	Source      string
 	Destination string
 	Attrs       map[string]string
+	// rel is the relationship shown by the edge, or nil for edges of
+	// layout hints.
+	rel *Relationship
+}
+
+// RenderDOT writes the graphviz input of a view of the model.
+func RenderDOT(w io.Writer, view View, model Model) error {
+	return genDot(w, viewGraph(view, model))
+}
+
+// RenderSVG renders a view of the model as SVG graphic, using graphviz.
+func RenderSVG(w io.Writer, view View, model Model) error {
+	return renderGraph(w, viewGraph(view, model), "-Tsvg")
+}
+
+// RenderPNG renders a view of the model as PNG image, using graphviz. The
+// default resolution of 96 dpi is multiplied by scale.
+func RenderPNG(w io.Writer, view View, model Model, scale float64) error {
+	dpi := strconv.FormatFloat(96*scale, 'f', -1, 64)
+	return renderGraph(w, viewGraph(view, model), "-Tpng", "-Gdpi="+dpi)
+}
+
+// RenderPDF renders a view of the model as PDF document, using graphviz.
+func RenderPDF(w io.Writer, view View, model Model) error {
+	return renderGraph(w, viewGraph(view, model), "-Tpdf")
+}
+
+// viewGraph returns the graph of a view, with the view options of the model
+// applied.
+func viewGraph(view View, model Model) graph {
+	g := view.graph(model)
+	violations := g.highlightViolations(model)
+	if legend, _ := model.viewOption(view, "legend"); legend != "false" {
+		g.Legend = g.legend()
+		if violations {
+			g.Legend = append(g.Legend, violationLegend)
+		}
+	}
+
+	g.Layout = make(map[string]string)
+	for _, key := range layoutOptions {
+		if v, ok := model.viewOption(view, key); ok {
+			g.Layout[key] = v
+		}
+	}
+	g.applyHints(model.viewHints(view))
+
+	if g.Layout["splines"] == "ortho" {
+		// graphviz can not place labels of orthogonal edges, but it
+		// places external labels next to them.
+		for _, e := range g.Edges {
+			e.Attrs["xlabel"] = e.Attrs["label"]
+			delete(e.Attrs, "label")
+		}
+	}
+	return g
+}
+
+// LayoutOption returns the value of a layout option of the graph, or its
+// default value.
+func (g graph) LayoutOption(key string) string {
+	if v, ok := g.Layout[key]; ok {
+		return v
+	}
+	return defaultLayout[key]
+}
+
+// NodeSize returns the node size preset of the graph.
+func (g graph) NodeSize() nodeSize {
+	return nodeSizes[g.LayoutOption("nodesize")]
 }
 
-func renderGraph(w io.Writer, view View, model Model) error {
-	cmd := exec.Command("dot", "-Tsvg")
+// renderGraph runs graphviz with the given arguments on a graph and writes
+// its output to w.
+func renderGraph(w io.Writer, g graph, args ...string) error {
+	cmd := exec.Command("dot", args...)
 	in, err := cmd.StdinPipe()
 	if err != nil {
 		return err
@@ -84,8 +228,8 @@ func renderGraph(w io.Writer, view View, model Model) error {
 		return err
 	}
 
-	err = view.dot(in, model)
-	//err = view.dot(io.MultiWriter(in, os.Stdout), model)
+	err = genDot(in, g)
+	//err = genDot(io.MultiWriter(in, os.Stdout), g)
 	if err != nil {
 		return err
 	}
@@ -112,7 +256,7 @@ func renderGraph(w io.Writer, view View, model Model) error {
 	return nil
 }
 
-func (v componentView) dot(w io.Writer, model Model) error {
+func (v componentView) graph(model Model) graph {
 	coreNodes := make([]node, 0)
 	topNodes := make([]node, 0)
 	bottomNodes := make([]node, 0)
@@ -151,21 +295,22 @@ func (v componentView) dot(w io.Writer, model Model) error {
 
 	edges = append(edges, relationshipEdges(model.FindRelationships(names)...)...)
 
-	// invert edges to top nodes to get ranking right
-	for i, edge := range edges {
+	// containers are placed above the components which use them. The
+	// edges to containers do not affect the ranking, so that they do not
+	// pull the containers below the components.
+	hintEdges := make([]edge, 0)
+	for _, edge := range edges {
 		if _, ok := model.Containers[edge.Destination]; ok {
-			src := edge.Source
-			edge.Source = edge.Destination
-			edge.Destination = src
-			edge.Attrs["dir"] = "back"
-			edges[i] = edge
+			edge.Attrs["constraint"] = "false"
+			hintEdges = append(hintEdges, aboveEdge(edge.Destination, edge.Source))
 		}
 	}
 
-	return genDot(w, graph{Title: v.title, CoreNodes: coreNodes, TopNodes: topNodes, BottomNodes: bottomNodes, Edges: edges})
+	return graph{Title: v.title, Boundary: v.boundary(), CoreNodes: coreNodes, TopNodes: topNodes, BottomNodes: bottomNodes, Edges: edges,
+		HintEdges: hintEdges}
 }
 
-func (v containerView) dot(w io.Writer, model Model) error {
+func (v containerView) graph(model Model) graph {
 	coreNodes := make([]node, 0)
 	topNodes := make([]node, 0)
 	bottomNodes := make([]node, 0)
@@ -204,10 +349,10 @@ func (v containerView) dot(w io.Writer, model Model) error {
 
 	edges = append(edges, relationshipEdges(model.FindRelationships(names)...)...)
 
-	return genDot(w, graph{Title: v.title, CoreNodes: coreNodes, TopNodes: topNodes, BottomNodes: bottomNodes, Edges: edges})
+	return graph{Title: v.title, Boundary: v.boundary(), CoreNodes: coreNodes, TopNodes: topNodes, BottomNodes: bottomNodes, Edges: edges}
 }
 
-func (v systemContextView) dot(w io.Writer, model Model) error {
+func (v systemContextView) graph(model Model) graph {
 	coreNodes := make([]node, 0)
 	topNodes := make([]node, 0)
 	bottomNodes := make([]node, 0)
@@ -246,7 +391,7 @@ func (v systemContextView) dot(w io.Writer, model Model) error {
 
 	edges = append(edges, relationshipEdges(model.FindRelationships(names)...)...)
 
-	return genDot(w, graph{Title: v.title, CoreNodes: coreNodes, TopNodes: topNodes, BottomNodes: bottomNodes, Edges: edges})
+	return graph{Title: v.title, Boundary: v.boundary(), CoreNodes: coreNodes, TopNodes: topNodes, BottomNodes: bottomNodes, Edges: edges}
 }
 
 func genDot(w io.Writer, g graph) error {
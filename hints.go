@@ -0,0 +1,207 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"strconv"
+	"strings"
+)
+
+// edgeOptions contains the graphviz attributes which can be set for
+// relationships with EdgeOptions, together with a function which reports
+// whether a value is valid.
+var edgeOptions = map[string]func(string) bool{
+	"weight":     isCount,
+	"minlen":     isCount,
+	"constraint": oneOf("true", "false"),
+}
+
+func isCount(v string) bool {
+	i, err := strconv.Atoi(v)
+	return err == nil && i >= 0
+}
+
+func (m *Model) addHint(path string, lineno int, h LayoutHint) {
+	m.hintPositions = append(m.hintPositions, position{File: path, Line: lineno})
+	m.LayoutHints = append(m.LayoutHints, h)
+}
+
+func parseSameRank(m *Model, path string, lineno int, value string) {
+	fields := strings.Split(value, "|")
+	if len(fields) != 2 {
+		m.addErr(path, lineno, "SameRank requires 2 elements: View | Elements")
+		if len(fields) < 2 {
+			return
+		}
+	}
+
+	elements := nonEmpty(parseTags(fields[1]))
+	if len(elements) < 2 {
+		m.addErr(path, lineno, "SameRank requires at least 2 elements")
+		return
+	}
+	m.addHint(path, lineno, LayoutHint{Kind: "SameRank", View: strings.TrimSpace(fields[0]), Elements: elements})
+}
+
+func parseAbove(m *Model, path string, lineno int, value string) {
+	fields := strings.Split(value, "|")
+	if len(fields) != 3 {
+		m.addErr(path, lineno, "Above requires 3 elements: View | Upper Element | Lower Element")
+		if len(fields) < 3 {
+			return
+		}
+	}
+
+	upper := strings.TrimSpace(fields[1])
+	lower := strings.TrimSpace(fields[2])
+	m.addHint(path, lineno, LayoutHint{Kind: "Above", View: strings.TrimSpace(fields[0]), Elements: []string{upper, lower}})
+}
+
+func parsePin(m *Model, path string, lineno int, value string) {
+	fields := strings.Split(value, "|")
+	if len(fields) != 3 {
+		m.addErr(path, lineno, "Pin requires 3 elements: View | Element | Position")
+		if len(fields) < 3 {
+			return
+		}
+	}
+
+	element := strings.TrimSpace(fields[1])
+	pos := strings.TrimSpace(fields[2])
+	if pos != "top" && pos != "bottom" {
+		m.addErr(path, lineno, "invalid position of Pin, must be top or bottom: "+pos)
+		return
+	}
+	m.addHint(path, lineno, LayoutHint{Kind: "Pin", View: strings.TrimSpace(fields[0]), Elements: []string{element}, Position: pos})
+}
+
+func parseEdgeOptions(m *Model, path string, lineno int, value string) {
+	fields := strings.Split(value, "|")
+	if len(fields) != 4 {
+		m.addErr(path, lineno, "EdgeOptions requires 4 elements: View | Source Name | Destination Name | Options")
+		if len(fields) < 4 {
+			return
+		}
+	}
+
+	source := strings.TrimSpace(fields[1])
+	destination := strings.TrimSpace(fields[2])
+	options := make(map[string]string)
+	for _, opt := range nonEmpty(parseTags(fields[3])) {
+		i := strings.Index(opt, "=")
+		if i == -1 {
+			m.addErr(path, lineno, "edge option requires a value: "+opt)
+			continue
+		}
+		key := strings.TrimSpace(opt[:i])
+		val := strings.TrimSpace(opt[i+1:])
+		valid, ok := edgeOptions[key]
+		if !ok {
+			m.addErr(path, lineno, "unknown edge option: "+key)
+			continue
+		}
+		if !valid(val) {
+			m.addErr(path, lineno, "invalid value of edge option "+key+": "+val)
+			continue
+		}
+		options[key] = val
+	}
+	m.addHint(path, lineno, LayoutHint{Kind: "EdgeOptions", View: strings.TrimSpace(fields[0]),
+		Elements: []string{source, destination}, Options: options})
+}
+
+// validateHints checks the views and elements referenced by layout hints.
+func (m *Model) validateHints() {
+	for i, h := range m.LayoutHints {
+		pos := position{}
+		if i < len(m.hintPositions) {
+			pos = m.hintPositions[i]
+		}
+		if _, ok := m.View(h.View); !ok && h.View != "" {
+			m.addErr(pos.File, pos.Line, "View of "+h.Kind+" is not defined: "+h.View)
+		}
+		for _, name := range h.Elements {
+			if _, _, ok := m.Element(name); !ok {
+				m.addErr(pos.File, pos.Line, "Element of "+h.Kind+" is not defined: "+name)
+			}
+		}
+	}
+}
+
+// viewHints returns the layout hints which apply to the view.
+func (m Model) viewHints(view View) []LayoutHint {
+	hints := make([]LayoutHint, 0)
+	for _, h := range m.LayoutHints {
+		if h.View == "" || h.View == view.ID() {
+			hints = append(hints, h)
+		}
+	}
+	return hints
+}
+
+// aboveEdge returns an invisible edge, which places upper above lower.
+func aboveEdge(upper, lower string) edge {
+	return edge{Source: upper, Destination: lower}
+}
+
+// applyHints translates layout hints to rank hints, invisible edges and
+// attributes of the edges of the graph.
+func (g *graph) applyHints(hints []LayoutHint) {
+	clusters := make(map[string]string)
+	for cluster, nodes := range map[string][]node{"core": g.CoreNodes, "top": g.TopNodes, "bottom": g.BottomNodes} {
+		for _, n := range nodes {
+			clusters[n.Name] = cluster
+		}
+	}
+	// cluster returns the cluster of all names, or "" if they are
+	// located in different clusters.
+	cluster := func(names []string) string {
+		c := clusters[names[0]]
+		for _, name := range names[1:] {
+			if clusters[name] != c {
+				return ""
+			}
+		}
+		return c
+	}
+
+	for _, h := range hints {
+		shown := make([]string, 0)
+		for _, name := range h.Elements {
+			if _, ok := clusters[name]; ok {
+				shown = append(shown, name)
+			}
+		}
+
+		switch h.Kind {
+		case "SameRank":
+			if len(shown) > 1 {
+				g.RankHints = append(g.RankHints, rankHint{Cluster: cluster(shown), Rank: "same", Nodes: shown})
+			}
+		case "Pin":
+			if len(shown) == 1 {
+				rank := map[string]string{"top": "min", "bottom": "max"}[h.Position]
+				g.RankHints = append(g.RankHints, rankHint{Cluster: cluster(shown), Rank: rank, Nodes: shown})
+			}
+		case "Above":
+			if len(shown) == 2 {
+				g.HintEdges = append(g.HintEdges, aboveEdge(shown[0], shown[1]))
+			}
+		case "EdgeOptions":
+			if len(shown) != 2 {
+				continue
+			}
+			for _, e := range g.Edges {
+				if e.Source != shown[0] || e.Destination != shown[1] {
+					continue
+				}
+				for k, v := range h.Options {
+					e.Attrs[k] = v
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const hintsSource = `System = Sys | |
+Container = Sys | App | | |
+Container = Sys | DB | | |
+Container = Sys | Cache | | |
+Relationship = App | Reads | SQL | DB |
+SameRank = containers/Sys | DB, Cache
+Above = | DB | App
+Pin = containers/Sys | App | bottom
+EdgeOptions = | App | DB | weight=2, constraint=false
+SameRank = | DB
+Pin = | App | left
+EdgeOptions = containers/Other | App | Unknown | weight=-1
+`
+
+func TestParseLayoutHints(t *testing.T) {
+	m, err := ParseReader("test.c4", strings.NewReader(hintsSource))
+	assertEqual(t, nil, err, "ParseReader returned an error")
+
+	assertEqual(t, []LayoutHint{
+		{Kind: "SameRank", View: "containers/Sys", Elements: []string{"DB", "Cache"}},
+		{Kind: "Above", View: "", Elements: []string{"DB", "App"}},
+		{Kind: "Pin", View: "containers/Sys", Elements: []string{"App"}, Position: "bottom"},
+		{Kind: "EdgeOptions", View: "", Elements: []string{"App", "DB"}, Options: map[string]string{"weight": "2", "constraint": "false"}},
+		{Kind: "EdgeOptions", View: "containers/Other", Elements: []string{"App", "Unknown"}, Options: map[string]string{}},
+	}, m.LayoutHints, "layout hints do not match")
+
+	errs := make([]string, 0)
+	for _, err := range m.Errors {
+		if !IsWarning(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+	assertEqual(t, []string{
+		"test.c4:10: SameRank requires at least 2 elements",
+		"test.c4:11: invalid position of Pin, must be top or bottom: left",
+		"test.c4:12: invalid value of edge option weight: -1",
+		"test.c4:12: View of EdgeOptions is not defined: containers/Other",
+		"test.c4:12: Element of EdgeOptions is not defined: Unknown",
+	}, errs, "errors do not match")
+}
+
+func TestApplyLayoutHints(t *testing.T) {
+	m, err := ParseReader("test.c4", strings.NewReader(hintsSource))
+	assertEqual(t, nil, err, "ParseReader returned an error")
+
+	view, _ := m.View("containers/Sys")
+	g := viewGraph(view, m)
+	assertEqual(t, []rankHint{
+		{Cluster: "core", Rank: "same", Nodes: []string{"DB", "Cache"}},
+		{Cluster: "core", Rank: "max", Nodes: []string{"App"}},
+	}, g.RankHints, "rank hints do not match")
+	assertEqual(t, []edge{{Source: "DB", Destination: "App"}}, g.HintEdges, "hint edges do not match")
+	assertEqual(t, "2", g.Edges[0].Attrs["weight"], "edge weight expected")
+	assertEqual(t, "false", g.Edges[0].Attrs["constraint"], "edge constraint expected")
+
+	buf := new(bytes.Buffer)
+	err = genDot(buf, g)
+	assertEqual(t, nil, err, "genDot returned an error")
+	assertEqual(t, true, strings.Contains(buf.String(), `{ rank="same"; "DB"; "Cache"; }`), "rank hint expected")
+	assertEqual(t, true, strings.Contains(buf.String(), `"DB" -> "App" [ style="invis" class="layout-hint" ];`),
+		"hint edge expected")
+}
+
+func TestComponentViewRanksContainersAbove(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	view, _ := m.View("components/Web App")
+	g := viewGraph(view, m)
+	for _, e := range g.Edges {
+		_, inverted := e.Attrs["dir"]
+		assertEqual(t, false, inverted, "edges are not inverted anymore")
+		if e.Destination == "Database" {
+			assertEqual(t, "Content Server", e.Source, "edge direction does not match")
+			assertEqual(t, "false", e.Attrs["constraint"], "edge to container must not constrain ranking")
+		}
+	}
+	assertEqual(t, []edge{{Source: "Database", Destination: "Content Server"}}, g.HintEdges, "hint edges do not match")
+}
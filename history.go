@@ -0,0 +1,346 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"html/template"
+	"io"
+)
+
+const historyTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<title>{{.Title}}</title>
+	<style>
+	{{- template "layoutStyle"}}
+	{{- template "viewerStyle"}}
+	.history-section {
+		border-top: 1px solid #dddddd;
+		margin-top: 32px;
+	}
+	.history-controls {
+		display: flex;
+		align-items: center;
+		gap: 8px;
+		margin-bottom: 8px;
+	}
+	.history-controls input {
+		flex: 1 1 auto;
+	}
+	.history-meta {
+		color: #7b7b7b;
+		font-size: 14px;
+	}
+	.timeline {
+		border-collapse: collapse;
+		font-size: 14px;
+	}
+	.timeline th, .timeline td {
+		text-align: left;
+		padding: 4px 8px;
+		border-bottom: 1px solid #dddddd;
+	}
+	.change-added {
+		color: #2e7d32;
+	}
+	.change-removed {
+		color: #c62828;
+	}
+	.change-changed {
+		color: #a07000;
+	}
+	</style>
+</head>
+<body>
+	<nav class="sidebar">
+		<a href="#timeline"><b>Timeline</b></a>
+		<ul>
+			{{- range .Revisions}}
+			<li><a href="#{{.Anchor}}">{{.Commit.Time.Format "2006-01-02"}} {{.Commit.Subject}}</a></li>
+			{{- end}}
+		</ul>
+	</nav>
+	<div class="content">
+	<section>
+		<h1>{{.Title}}</h1>
+		<p>{{.Description}}</p>
+
+		<div class="history-controls">
+			<button type="button" id="history-play">Play</button>
+			<input type="range" id="history-slider" min="0" max="{{.Last}}" value="{{.Last}}">
+			<span id="history-label"></span>
+		</div>
+		{{- range $i, $f := .Frames}}
+		<div class="history-frame" data-label="{{.Title}}"{{if ne $i $.Last}} hidden{{end}}>
+			<p>{{.Description}}</p>
+
+			{{template "graphErrors" .}}
+
+			{{if .Svg}}{{template "viewer" .}}{{end}}
+
+			{{template "legend" .}}
+		</div>
+		{{- end}}
+	</section>
+
+	<section class="history-section" id="timeline">
+		<h2>Timeline</h2>
+		<table class="timeline">
+			<tr><th>Element</th><th>Kind</th><th>Appeared</th><th>Last changed</th><th>Disappeared</th></tr>
+			{{- range .Timeline}}
+			<tr>
+				<td>{{.Name}}</td>
+				<td>{{.Kind}}</td>
+				<td>{{template "revisionLink" .Appeared}}</td>
+				<td>{{template "revisionLink" .Changed}}</td>
+				<td>{{template "revisionLink" .Removed}}</td>
+			</tr>
+			{{- end}}
+		</table>
+	</section>
+
+	{{- range .Revisions}}
+
+	<section class="history-section" id="{{.Anchor}}">
+		<h2>{{.Commit.Subject}}</h2>
+		<p class="history-meta">{{.Commit.Hash}} &middot; {{.Commit.Author}} &middot; {{.Commit.Time.Format "2006-01-02 15:04"}}</p>
+		<ul>
+			{{- range .Changes}}
+			<li class="change-{{.Status}}">{{.Status}} {{.Kind}} <b>{{.Name}}</b>
+				{{- if .Fields}}
+				<ul>
+					{{- range .Fields}}
+					<li>{{.Field}}:
+						{{- if or .Added .Removed}}
+						{{- range .Added}} +{{.}}{{end}}{{range .Removed}} -{{.}}{{end}}
+						{{- else}} &ldquo;{{.Old}}&rdquo; &rarr; &ldquo;{{.New}}&rdquo;{{end}}</li>
+					{{- end}}
+				</ul>
+				{{- end}}
+			</li>
+			{{- end}}
+		</ul>
+	</section>
+	{{- end}}
+	</div>
+
+	<script>
+	(function() {
+		var slider = document.getElementById("history-slider");
+		var label = document.getElementById("history-label");
+		var play = document.getElementById("history-play");
+		var frames = document.querySelectorAll(".history-frame");
+		if (frames.length === 0) {
+			return;
+		}
+
+		function show(i) {
+			frames.forEach(function(f, j) {
+				f.hidden = j !== i;
+			});
+			slider.value = i;
+			label.textContent = frames[i].getAttribute("data-label");
+		}
+
+		var timer = null;
+		function stop() {
+			clearInterval(timer);
+			timer = null;
+			play.textContent = "Play";
+		}
+
+		slider.addEventListener("input", function() {
+			stop();
+			show(Number(slider.value));
+		});
+		play.addEventListener("click", function() {
+			if (timer) {
+				stop();
+				return;
+			}
+			var i = Number(slider.value);
+			if (i >= frames.length - 1) {
+				i = 0;
+				show(i);
+			}
+			play.textContent = "Pause";
+			timer = setInterval(function() {
+				if (i >= frames.length - 1) {
+					stop();
+					return;
+				}
+				show(++i);
+			}, 1500);
+		});
+		show(frames.length - 1);
+	})();
+	</script>
+</body>
+</html>
+
+{{- define "revisionLink"}}
+	{{- with .}}<a href="#{{.Anchor}}">{{.Commit.Time.Format "2006-01-02"}}</a>{{end}}
+{{- end}}
+`
+
+// A Revision is the state of a project at a commit which changed it.
+type Revision struct {
+	Commit GitCommit
+	Model  Model
+}
+
+// History returns the revisions of the project located in path, oldest
+// first. It follows the first parents of the given revision and only
+// includes the commits which changed the files of the project. Revisions in
+// which the project did not exist have an empty model.
+func History(path, rev string) ([]Revision, error) {
+	r, err := OpenGitRepo(path)
+	if err != nil {
+		return nil, err
+	}
+	root, err := r.relPath(path)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := r.ResolveRevision(rev)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := r.Log(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	revs := make([]Revision, 0)
+	prevTree := ""
+	for i := len(commits) - 1; i >= 0; i-- {
+		c := commits[i]
+		tree, err := r.TreeHash(c.Hash, root)
+		if err != nil {
+			return nil, err
+		}
+		if tree == prevTree {
+			continue
+		}
+		prevTree = tree
+
+		m := *newModel()
+		if tree != "" {
+			m, err = r.parseCommit(c.Hash, root, c.Hash[:7])
+			if err != nil {
+				return nil, err
+			}
+		}
+		revs = append(revs, Revision{Commit: c, Model: m})
+	}
+	return revs, nil
+}
+
+// history is the HTML timeline of the revisions of a project.
+type history struct {
+	page
+	// Frames contain the view at every revision, Last is the index of the
+	// latest one.
+	Frames    []page
+	Last      int
+	Revisions []historyRevision
+	Timeline  []*timelineEntry
+}
+
+// A historyRevision is a revision together with its changes to the previous
+// one.
+type historyRevision struct {
+	Anchor  string
+	Commit  GitCommit
+	Changes []Change
+}
+
+// A timelineEntry lists the revisions in which an element or relationship
+// appeared, changed for the last time and disappeared.
+type timelineEntry struct {
+	Kind     string
+	Name     string
+	Appeared *historyRevision
+	Changed  *historyRevision
+	Removed  *historyRevision
+}
+
+// RenderHTMLHistory creates a single, self-contained HTML page showing the
+// history of a project: a slider to step through the view with the given ID
+// at every revision, with the changes to the previous revision highlighted,
+// a timeline of all elements and relationships, and the changes of every
+// revision.
+func RenderHTMLHistory(w io.Writer, revisions []Revision, viewID string) error {
+	h := history{
+		page: page{
+			Title:       "Architecture History",
+			Description: "The view " + viewID + " at every revision which changed the project.",
+		},
+		Frames:    make([]page, 0),
+		Last:      len(revisions) - 1,
+		Revisions: make([]historyRevision, len(revisions)),
+		Timeline:  make([]*timelineEntry, 0),
+	}
+
+	entries := make(map[string]*timelineEntry)
+	prev := *newModel()
+	for i, rev := range revisions {
+		d := Diff(prev, rev.Model)
+		prev = rev.Model
+
+		hr := &h.Revisions[i]
+		*hr = historyRevision{Anchor: "rev-" + rev.Commit.Hash, Commit: rev.Commit, Changes: d.Changes}
+		for _, c := range d.Changes {
+			if c.Kind == "View" {
+				continue
+			}
+			e, ok := entries[c.Kind+":"+c.Name]
+			if !ok {
+				e = &timelineEntry{Kind: c.Kind, Name: c.Name}
+				entries[c.Kind+":"+c.Name] = e
+				h.Timeline = append(h.Timeline, e)
+			}
+			switch c.Status {
+			case "added":
+				if e.Appeared == nil {
+					e.Appeared = hr
+				}
+				e.Removed = nil
+			case "changed":
+				e.Changed = hr
+			case "removed":
+				e.Removed = hr
+			}
+		}
+
+		frame := page{
+			Title:  rev.Commit.Hash[:7] + " " + rev.Commit.Time.Format("2006-01-02") + " " + rev.Commit.Subject(),
+			Anchor: hr.Anchor,
+		}
+		g, err := d.graph(viewID)
+		if err != nil {
+			frame.Description = "The view does not exist in this revision."
+		} else {
+			// the links of the nodes lead to pages which are not part
+			// of the history.
+			for _, nodes := range [][]node{g.CoreNodes, g.TopNodes, g.BottomNodes} {
+				for _, n := range nodes {
+					delete(n.Attrs, "URL")
+				}
+			}
+			frame.setSvg(g)
+		}
+		h.Frames = append(h.Frames, frame)
+	}
+
+	t := template.Must(template.New("layoutTemplate").Parse(layoutTemplate))
+	t = template.Must(t.Parse(viewerTemplate))
+	t = template.Must(t.Parse(searchTemplate))
+	t = template.Must(t.New("historyTemplate").Parse(historyTemplate))
+	return t.ExecuteTemplate(w, "historyTemplate", h)
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	dir := gitRepo(t)
+
+	revs, err := History(filepath.Join(dir, "arch"), "HEAD")
+	assertEqual(t, nil, err, "History returned an error")
+	assertEqual(t, 2, len(revs), "revisions do not match")
+	assertEqual(t, "initial architecture", revs[0].Commit.Subject(), "revisions must be ordered oldest first")
+	_, ok := revs[1].Model.Systems["Newsletter"]
+	assertEqual(t, true, ok, "model of revision expected")
+
+	// the project did not change in the latest commit.
+	revs, err = History(filepath.Join(dir, "arch", "container.c4"), "HEAD")
+	assertEqual(t, nil, err, "History returned an error")
+	assertEqual(t, 1, len(revs), "revisions of a single file do not match")
+}
+
+func TestRenderHTMLHistory(t *testing.T) {
+	dir := gitRepo(t)
+	revs, err := History(filepath.Join(dir, "arch"), "HEAD")
+	assertEqual(t, nil, err, "History returned an error")
+
+	buf := new(bytes.Buffer)
+	err = RenderHTMLHistory(buf, revs, "contexts/index")
+	assertEqual(t, nil, err, "RenderHTMLHistory returned an error")
+	out := buf.String()
+
+	anchor := "rev-" + revs[1].Commit.Hash
+	assertEqual(t, 2, strings.Count(out, `class="history-frame"`), "one frame per revision expected")
+	assertEqual(t, true, strings.Contains(out, `<td>Newsletter</td>
+				<td>System</td>
+				<td><a href="#`+anchor+`">`), "timeline entry expected")
+	assertEqual(t, true, strings.Contains(out, `<section class="history-section" id="`+anchor+`">`),
+		"revision section expected")
+	assertEqual(t, true, strings.Contains(out, `<li class="change-added">added System <b>Newsletter</b>`),
+		"change of revision expected")
+}
@@ -7,19 +7,149 @@ package blueprint
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"html/template"
 	"io"
 )
 
-const pageTemplate = `
+const layoutTemplate = `
 <!DOCTYPE html>
 <html>
 <head>
 	<meta charset="UTF-8">
 	<title>{{.Title}}</title>
 	<style>
+	{{- template "layoutStyle"}}
+	{{- template "viewerStyle"}}
+	{{- template "searchStyle"}}
+	</style>
+</head>
+<body>
+	<nav class="sidebar">
+		<a href="{{.Root}}index.html"><b>Index</b></a>
+		{{template "search" .}}
+		{{template "navTree" .}}
+	</nav>
+	<div class="content">
+	<div class="breadcrumbs">
+		{{- range .Breadcrumbs}}<a href="{{$.Root}}{{.URL}}">{{.Title}}</a> &rsaquo; {{end}}{{.Title -}}
+	</div>
+
+	{{template "content" .}}
+
+	<div class="pager">
+		<span>{{with .Prev}}&larr; <a href="{{$.Root}}{{.URL}}">{{.Title}}</a>{{end}}</span>
+		<span>{{with .Next}}<a href="{{$.Root}}{{.URL}}">{{.Title}}</a> &rarr;{{end}}</span>
+	</div>
+	</div>
+</body>
+</html>
+
+{{- define "navTree"}}
+<ul>
+	{{- range .Nav}}
+	<li><a href="{{$.Root}}{{.URL}}"{{if .Active}} class="active"{{end}}>{{.Title}}</a>
+	{{- if .Children}}{{template "navTree" ($.WithNav .Children)}}{{end}}</li>
+	{{- end}}
+</ul>
+{{- end}}
+
+{{- define "modelErrors"}}
+	{{- if .ModelErrors -}}
+	<div><div class="danger panel"><div class="panel-margin">
+		Model Errors
+		<pre>{{range .ModelErrors}}{{.Error}}<br/>{{end}}</pre>
+	</div></div></div>
+	{{- end}}
+
+	{{- if .ModelWarnings -}}
+	<div><div class="warning panel"><div class="panel-margin">
+		Model Warnings
+		<pre>{{range .ModelWarnings}}{{.Error}}<br/>{{end}}</pre>
+	</div></div></div>
+	{{- end}}
+{{- end}}
+
+{{- define "graphErrors"}}
+	{{- if .GenError -}}
+	<div><div class="danger panel"><div class="panel-margin">
+		Graphviz Errors
+		<pre>{{.GenError.Error}}</pre>
+	</div></div></div>
+	{{- end}}
+
+	{{if .GenWarning -}}
+	<div><div class="warning panel"><div class="panel-margin">
+		Graphviz Warnings
+		<pre>{{.GenWarning.Error}}</pre>
+	</div></div></div>
+	{{- end}}
+{{- end}}
+
+{{- define "legend"}}
+	{{- if .Legend -}}
+	<table class="legend">
+		<caption>Legend</caption>
+		{{- range .Legend}}
+		<tr>
+			<td><span class="legend-{{.Style}}"{{if .Color}} style="background-color: {{.Color}}; border-color: {{.BorderColor}}"{{end}}>
+				{{- .Kind}}{{if eq .Style "relationship"}} &rarr;{{end -}}
+			</span></td>
+			<td>{{.Description}}</td>
+		</tr>
+		{{- end}}
+	</table>
+	{{- end}}
+{{- end}}
+
+{{- define "layoutStyle"}}
 	body {
 		font-family: Sans;
+		margin: 0;
+		display: flex;
+	}
+	.sidebar {
+		flex: 0 0 280px;
+		min-height: 100vh;
+		padding: 16px;
+		background-color: #f4f4f4;
+		border-right: 1px solid #dddddd;
+		font-size: 14px;
+	}
+	.sidebar ul {
+		list-style: none;
+		padding-left: 12px;
+	}
+	.sidebar > ul {
+		padding-left: 0;
+	}
+	.sidebar li {
+		margin-top: 4px;
+	}
+	.sidebar .active {
+		font-weight: bold;
+	}
+	.content {
+		flex: 1 1 auto;
+		padding: 0 16px;
+		min-width: 0;
+	}
+	.breadcrumbs {
+		margin-top: 16px;
+		font-size: 14px;
+		color: #7b7b7b;
+	}
+	.pager {
+		display: flex;
+		justify-content: space-between;
+		margin-top: 16px;
+		margin-bottom: 16px;
+	}
+	a {
+		color: #1168bd;
+		text-decoration: none;
 	}
 	.panel-margin {
 		margin-top: 16px;
@@ -48,45 +178,205 @@ const pageTemplate = `
 		background-color: #ffffcc;
 		border-left: 6px solid #ffeb3b;
 	}
-	</style>
-</head>
-<body>
+	.legend {
+		margin-top: 16px;
+		font-size: 14px;
+		border-spacing: 4px;
+	}
+	.legend caption {
+		text-align: left;
+		font-weight: bold;
+	}
+	.legend span {
+		display: inline-block;
+		min-width: 100px;
+		padding: 4px 8px;
+		box-sizing: border-box;
+	}
+	.legend-element {
+		color: white;
+		border: 1px solid;
+		border-radius: 4px;
+	}
+	.legend-boundary {
+		border: 2px dashed #7b7b7b;
+		border-radius: 4px;
+	}
+	.legend-relationship {
+		color: dimgrey;
+	}
+{{- end}}
+`
+
+const pageTemplate = `
+{{- define "content"}}
 	<h1>{{.Title}}</h1>
 	<p>{{.Description}}</p>
 
-	{{if .ModelErrors -}}
-	<div><div class="danger panel"><div class="panel-margin">
-		Model Errors
-		<pre>{{range .ModelErrors}}{{.Error}}<br/>{{end}}</pre>
-	</div></div></div>
-	{{- end}}
+	{{template "modelErrors" .}}
 
-	{{if .GenError -}}
-	<div><div class="danger panel"><div class="panel-margin">
-		Graphviz Errors
-		<pre>{{.GenError.Error}}</pre>
-	</div></div></div>
+	{{template "graphErrors" .}}
+
+	{{if .Svg}}{{template "viewer" .}}{{end}}
+
+	{{template "legend" .}}
+
+	{{- if .Impact}}
+	<p class="impact">Impact of an outage:
+		{{- range $i, $l := .Impact}}{{if $i}},{{end}} <a href="{{$.Root}}{{$l.URL}}">{{$l.Title}}</a>{{end}}</p>
 	{{- end}}
 
-	{{if .GenWarning -}}
-	<div><div class="warning panel"><div class="panel-margin">
-		Graphviz Warnings
-		<pre>{{.GenWarning.Error}}</pre>
-	</div></div></div>
+	{{- range .APIs}}
+	<p class="api">API: {{.Title}}{{with .Version}} {{.}}{{end}} [{{.Specification}}: {{.File}}]</p>
+	{{- if .Operations}}
+	<ul>
+		{{- range .Operations}}
+		<li><code>{{.String}}</code>{{with .Summary}} {{.}}{{end}}</li>
+		{{- end}}
+	</ul>
+	{{- end}}
 	{{- end}}
+{{- end}}
+`
 
-	<div>{{.Svg}}</div>
-</body>
-</html>
+const indexTemplate = `
+{{- define "content"}}
+	<h1>{{.Title}}</h1>
+	<p>{{.Description}}</p>
+
+	{{template "modelErrors" .}}
+
+	{{template "indexList" .}}
+{{- end}}
+
+{{- define "indexList"}}
+<ul>
+	{{- range .Nav}}
+	<li><a href="{{$.Root}}{{.URL}}">{{.Title}}</a>{{if .Description}} &ndash; {{.Description}}{{end}}
+	{{- if .Children}}{{template "indexList" ($.WithNav .Children)}}{{end}}</li>
+	{{- end}}
+</ul>
+{{- end}}
 `
 
 type page struct {
+	Title         string
+	Description   string
+	Svg           template.HTML
+	ModelErrors   []error
+	ModelWarnings []error
+	GenError      error
+	GenWarning    error
+
+	// Root is the relative path from the page to the project root. All
+	// navigation URLs are relative to the project root, so the exported
+	// pages can be browsed without a server.
+	Root        string
+	Nav         []*navLink
+	Breadcrumbs []*navLink
+	Prev        *navLink
+	Next        *navLink
+	// Impact links to the impact views of the elements of the view.
+	Impact []*navLink
+	// APIs are the APIs of the container the view is centered on.
+	APIs []API
+
+	// Anchor is the id of the section of a view within the HTML report,
+	// which contains more than one view. SearchIndex is inlined into
+	// pages which can not load search.js, like the report.
+	Anchor      string
+	SearchIndex []SearchEntry
+	Legend      []legendEntry
+}
+
+// DiagramID returns the HTML id of the diagram viewer of the page.
+func (p page) DiagramID() string {
+	if p.Anchor == "" {
+		return "diagram"
+	}
+	return "diagram-" + p.Anchor
+}
+
+// WithNav returns a copy of the page with a different navigation tree, which
+// is used to render the tree recursively.
+func (p page) WithNav(nav []*navLink) page {
+	p.Nav = nav
+	return p
+}
+
+// A navLink is a link to a view, as shown in the sidebar, breadcrumbs and
+// index of the HTML pages.
+type navLink struct {
 	Title       string
 	Description string
-	Svg         template.HTML
-	ModelErrors []error
-	GenError    error
-	GenWarning  error
+	URL         string
+	Active      bool
+	Children    []*navLink
+}
+
+func newNavLink(v View) *navLink {
+	return &navLink{Title: v.Title(), Description: v.Description(), URL: v.path()}
+}
+
+// siteNav builds the navigation tree of the views of the model, as returned
+// by Model.Views, following the C4 hierarchy from the system contexts down
+// to the containers. The view located at active is marked as such.
+func siteNav(model Model, views []View, active string) []*navLink {
+	roots := make([]*navLink, 0)
+	links := make(map[string]*navLink)
+	for _, v := range views {
+		l := newNavLink(v)
+		l.Active = l.URL == active
+		links[l.URL] = l
+
+		parent := v.parent(model)
+		if parent == nil {
+			roots = append(roots, l)
+			continue
+		}
+		if pl, ok := links[parent.path()]; ok {
+			pl.Children = append(pl.Children, l)
+		} else {
+			roots = append(roots, l)
+		}
+	}
+	return roots
+}
+
+// setNav fills all navigation elements of a page showing the given view.
+// views are all views of the model, as returned by Model.Views.
+func (p *page) setNav(view View, model Model, views []View) {
+	p.Root = "../"
+	p.Nav = siteNav(model, views, view.path())
+	p.setBreadcrumbs(view, model)
+
+	for _, name := range view.Elements() {
+		if _, _, ok := model.Element(name); ok {
+			p.Impact = append(p.Impact, &navLink{Title: name, URL: impactPath(name)})
+		}
+	}
+
+	for i, v := range views {
+		if v.path() != view.path() {
+			continue
+		}
+		if i > 0 {
+			p.Prev = newNavLink(views[i-1])
+		}
+		if i < len(views)-1 {
+			p.Next = newNavLink(views[i+1])
+		}
+		break
+	}
+}
+
+// setBreadcrumbs fills the breadcrumbs of a page showing the given view,
+// which lead from the index to the view.
+func (p *page) setBreadcrumbs(view View, model Model) {
+	for v := view.parent(model); v != nil; v = v.parent(model) {
+		p.Breadcrumbs = append([]*navLink{newNavLink(v)}, p.Breadcrumbs...)
+	}
+	p.Breadcrumbs = append([]*navLink{{Title: "Index", URL: "index.html"}}, p.Breadcrumbs...)
 }
 
 // RenderHTMLPage creates a HTML page for a certain view of the model.
@@ -95,14 +385,27 @@ type page struct {
 // An error is only returned if critical errors occur during the rendering of
 // the actual graph, or HTML page.
 func RenderHTMLPage(w io.Writer, view View, model Model) error {
-	p := page{
-		Title:       view.Title(),
-		Description: view.Description(),
-		ModelErrors: model.Errors,
-	}
+	p := newViewPage(view, model, model.Views())
+	p.setGraph(view, model)
+
+	return execPage(w, pageTemplate, p)
+}
+
+// setGraph renders the graph of the view shown by the page. Errors of
+// graphviz are shown in the page.
+func (p *page) setGraph(view View, model Model) {
+	p.setSvg(viewGraph(view, model))
+}
+
+// setSvg renders a graph as the SVG graphic of the page.
+func (p *page) setSvg(g graph) {
+	// the legend is shown as table below the graph, instead of being
+	// part of the graph itself.
+	p.Legend = g.Legend
+	g.Legend = nil
 
 	svgBuf := new(bytes.Buffer)
-	err := renderGraph(svgBuf, view, model)
+	err := renderGraph(svgBuf, g, "-Tsvg")
 	if err != nil && svgBuf.Len() == 0 {
 		p.GenError = err
 	} else if err != nil {
@@ -110,7 +413,80 @@ func RenderHTMLPage(w io.Writer, view View, model Model) error {
 	}
 
 	p.Svg = template.HTML(svgBuf.String())
+}
+
+// newViewPage returns the page of a view, without its rendered graph.
+// views are all views of the model, as returned by Model.Views.
+func newViewPage(view View, model Model, views []View) page {
+	p := page{
+		Title:         view.Title(),
+		Description:   view.Description(),
+		ModelErrors:   model.Errors,
+		ModelWarnings: model.Warnings,
+	}
+	p.setNav(view, model, views)
+	p.APIs = viewAPIs(view, model)
+	return p
+}
+
+// viewAPIs returns the APIs exposed by the container a view is centered on,
+// i.e. the container of a component view, or the element of an impact view.
+func viewAPIs(view View, model Model) []API {
+	switch v := view.(type) {
+	case componentView:
+		return model.APISpecs[v.Container].APIs
+	case impactView:
+		return model.APISpecs[v.Element].APIs
+	}
+	return nil
+}
+
+// PageHash returns a hash of everything the HTML page of a view is made of:
+// the graphviz input of the view, the rest of the page content, and the
+// templates and styles of the page. As long as the graphviz installation
+// does not change, pages with the same hash are identical, so the hash can
+// be used to skip rendering of unchanged views.
+func PageHash(view View, model Model) (string, error) {
+	h := sha256.New()
+	for _, t := range []string{layoutTemplate, viewerTemplate, searchTemplate, pageTemplate, dotTemplate} {
+		_, _ = io.WriteString(h, t)
+	}
+
+	err := RenderDOT(h, view, model)
+	if err != nil {
+		return "", err
+	}
+
+	err = json.NewEncoder(h).Encode(newViewPage(view, model, model.Views()))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// RenderHTMLIndex creates the HTML index page of the model, which lists all
+// of its views. The index page is located at the root of the project, so
+// the relative links of all other pages lead back to it.
+func RenderHTMLIndex(w io.Writer, model Model) error {
+	views := model.Views()
+	p := page{
+		Title:         "Index",
+		Description:   "All views of the current project.",
+		ModelErrors:   model.Errors,
+		ModelWarnings: model.Warnings,
+		Nav:           siteNav(model, views, "index.html"),
+	}
+	if len(views) > 0 {
+		p.Next = newNavLink(views[0])
+	}
+
+	return execPage(w, indexTemplate, p)
+}
 
-	t := template.Must(template.New("pageTemplate").Parse(pageTemplate))
+func execPage(w io.Writer, content string, p page) error {
+	t := template.Must(template.New("layoutTemplate").Parse(layoutTemplate))
+	t = template.Must(t.Parse(viewerTemplate))
+	t = template.Must(t.Parse(searchTemplate))
+	t = template.Must(t.Parse(content))
 	return t.Execute(w, p)
 }
@@ -0,0 +1,78 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRenderViewer(t *testing.T) {
+	p := page{Title: "Test Title", Svg: template.HTML(`<svg viewBox="0 0 10 10"></svg>`)}
+
+	buf := new(bytes.Buffer)
+	err := execPage(buf, pageTemplate, p)
+	assertEqual(t, nil, err, "execPage returned an error")
+	assertEqual(t, true, strings.Contains(buf.String(), `<div class="diagram" id="diagram">`),
+		"page does not contain the diagram viewer")
+	assertEqual(t, true, strings.Contains(buf.String(), `<svg viewBox="0 0 10 10"></svg>`),
+		"page does not contain the svg")
+}
+
+func TestRenderHTMLPageConcurrent(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	var wg sync.WaitGroup
+	for _, view := range m.Views() {
+		wg.Add(1)
+		go func(view View) {
+			defer wg.Done()
+			err := RenderHTMLPage(io.Discard, view, m)
+			if err != nil {
+				t.Errorf("%s: %v", view.ID(), err)
+			}
+		}(view)
+	}
+	wg.Wait()
+}
+
+func TestPageHash(t *testing.T) {
+	hashes := make(map[string]string)
+	for i := 0; i < 5; i++ {
+		m, err := Parse("test/ok")
+		assertEqual(t, nil, err, "Parse returned an error")
+		for _, view := range m.Views() {
+			hash, err := PageHash(view, m)
+			assertEqual(t, nil, err, "PageHash returned an error")
+			if prev, ok := hashes[view.ID()]; ok && prev != hash {
+				t.Errorf("%s: hash is not deterministic", view.ID())
+			}
+			hashes[view.ID()] = hash
+		}
+	}
+
+	distinct := make(map[string]bool)
+	for _, hash := range hashes {
+		distinct[hash] = true
+	}
+	assertEqual(t, len(hashes), len(distinct), "views with the same hash")
+}
+
+func TestRenderModelWarnings(t *testing.T) {
+	m, err := ParseReader("test.c4", strings.NewReader("System = Unused | |\n"))
+	assertEqual(t, nil, err, "ParseReader returned an error")
+
+	buf := new(bytes.Buffer)
+	err = RenderHTMLIndex(buf, m)
+	assertEqual(t, nil, err, "RenderHTMLIndex returned an error")
+	assertEqual(t, false, strings.Contains(buf.String(), "Model Errors"), "warnings must not be shown as errors")
+	assertEqual(t, true, strings.Contains(buf.String(), "Model Warnings"), "warnings are not shown")
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// imported contains the elements read from an external source, e.g. a
+// docker-compose file, to be merged into a project.
+type imported struct {
+	// source describes where the elements come from, e.g. import compose.
+	source        string
+	systems       []System
+	containers    []Container
+	relationships []Relationship
+	// tagPrefixes are the prefixes of the tags set by the import, e.g.
+	// network:. All other tags are kept when importing again.
+	tagPrefixes []string
+	// src is the source of the project file the elements are merged with,
+	// see readImportFile.
+	src []byte
+}
+
+// readImportFile reads the project file at path, which is merged with the
+// imported elements. A file which does not exist yet is empty.
+func readImportFile(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	src, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return src, err
+}
+
+// merge generates the source of the project file at path, which contains
+// the imported elements. Elements defined in other files of the project are
+// hand-written and left out. Elements defined in the file itself, by a
+// previous import, keep their description, their tags other than the
+// imported ones and, for relationships, their technology, so that the file
+// can be refined by hand and imported again.
+//
+// The statements of the file, given by imp.src, are updated in place. All other statements and
+// comments of the file are kept, including elements which are no longer
+// imported, and new elements are added after the last element of their kind.
+func (m Model) merge(path string, imp imported) []byte {
+	// own reports whether an element defined at pos belongs to the file.
+	own := func(pos position) bool {
+		return path != "" && samePath(pos.File, path)
+	}
+
+	// lines maps the keys of the imported elements to their statements,
+	// keys lists them in the order in which new elements are added.
+	lines := make(map[string]string)
+	keys := make([]string, 0)
+	add := func(key, line string) {
+		lines[key] = line
+		keys = append(keys, key)
+	}
+
+	for _, s := range imp.systems {
+		if kind, _, ok := m.Element(s.Name); ok && !own(m.positions[kind+":"+s.Name]) {
+			continue
+		}
+		if old, ok := m.Systems[s.Name]; ok {
+			s.Description = old.Description
+			s.Tags = mergeTags(old.Tags, s.Tags, imp.tagPrefixes)
+		}
+		add("System:"+s.Name, fmt.Sprintf("System = %s | %s | %s", s.Name, s.Description, strings.Join(s.Tags, ", ")))
+	}
+
+	sort.Slice(imp.containers, func(i, j int) bool { return imp.containers[i].Name < imp.containers[j].Name })
+	for _, c := range imp.containers {
+		if kind, _, ok := m.Element(c.Name); ok && !own(m.positions[kind+":"+c.Name]) {
+			continue
+		}
+		if old, ok := m.Containers[c.Name]; ok {
+			c.Description = old.Description
+			c.Tags = mergeTags(old.Tags, c.Tags, imp.tagPrefixes)
+		}
+		add("Container:"+c.Name, fmt.Sprintf("Container = %s | %s | %s | %s | %s", c.System, c.Name, c.Description,
+			c.Technology, strings.Join(c.Tags, ", ")))
+	}
+
+	sort.SliceStable(imp.relationships, func(i, j int) bool {
+		a, b := imp.relationships[i], imp.relationships[j]
+		return a.Source < b.Source || a.Source == b.Source && a.Destination < b.Destination
+	})
+	for _, r := range imp.relationships {
+		i := m.relationshipIndex(r.Source, r.Destination)
+		if i != -1 && !own(m.relPosition(i)) {
+			continue
+		}
+		if i != -1 {
+			old := m.Relationships[i]
+			r.Description = old.Description
+			if old.Technology != "" {
+				r.Technology = old.Technology
+			}
+			r.Tags = mergeTags(old.Tags, r.Tags, imp.tagPrefixes)
+		}
+		add("Relationship:"+r.Source+"->"+r.Destination, fmt.Sprintf("Relationship = %s | %s | %s | %s | %s",
+			r.Source, r.Description, r.Technology, r.Destination, strings.Join(r.Tags, ", ")))
+	}
+
+	f := ParseSyntax(path, imp.src)
+	last := make(map[string]int)
+	for i, stmt := range f.Stmts {
+		if key := importKey(stmt); key != "" {
+			kind, _, _ := strings.Cut(key, ":")
+			last[kind] = i
+		}
+	}
+
+	// added appends the new elements of a kind, which are not defined in the
+	// file yet.
+	var b strings.Builder
+	added := func(kind string) {
+		for _, key := range keys {
+			if line, ok := lines[key]; ok && strings.HasPrefix(key, kind+":") {
+				b.WriteString(line + "\n")
+				delete(lines, key)
+			}
+		}
+	}
+
+	if len(f.Stmts) == 0 {
+		fmt.Fprintf(&b, "# Imported by blueprint %s.\n", imp.source)
+		b.WriteString("# Descriptions and tags edited in this file are kept when importing again.\n")
+	}
+	for i, stmt := range f.Stmts {
+		key := importKey(stmt)
+		if line, ok := lines[key]; ok {
+			b.WriteString(line + "\n")
+			delete(lines, key)
+		} else {
+			b.WriteString(strings.Join(stmt.Lines, "\n") + "\n")
+		}
+		if key != "" {
+			kind, _, _ := strings.Cut(key, ":")
+			if last[kind] == i {
+				added(kind)
+			}
+		}
+	}
+	for _, kind := range []string{"System", "Container", "Relationship"} {
+		b.WriteString("\n")
+		added(kind)
+	}
+	return Format([]byte(b.String()))
+}
+
+// importKey returns the key of the element defined by a statement of a
+// project file, e.g. Container:web or Relationship:web->api, if it is of a
+// kind generated by imports.
+func importKey(stmt *Stmt) string {
+	if stmt.Kind != ElementStmt {
+		return ""
+	}
+	field := func(i int) string {
+		if i < len(stmt.Fields) {
+			return stmt.Fields[i].Text
+		}
+		return ""
+	}
+	switch keyword, _ := CanonicalKeyword(stmt.Keyword.Text); keyword {
+	case "System":
+		return "System:" + field(0)
+	case "Container":
+		return "Container:" + field(1)
+	case "Relationship":
+		return "Relationship:" + field(0) + "->" + field(3)
+	}
+	return ""
+}
+
+// relationshipIndex returns the index of the first relationship from source
+// to destination, or -1 if there is none.
+func (m Model) relationshipIndex(source, destination string) int {
+	for i, r := range m.Relationships {
+		if r.Source == source && r.Destination == destination {
+			return i
+		}
+	}
+	return -1
+}
+
+// mergeTags returns the tags of a previous import, without the imported
+// ones, followed by the newly imported tags.
+func mergeTags(old, imported, prefixes []string) []string {
+	tags := make([]string, 0)
+	for _, t := range nonEmpty(old) {
+		keep := true
+		for _, p := range prefixes {
+			keep = keep && !strings.HasPrefix(t, p)
+		}
+		if keep {
+			tags = append(tags, t)
+		}
+	}
+	return append(tags, imported...)
+}
+
+// samePath reports whether two paths refer to the same file.
+func samePath(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
+
+// importField replaces the separators of the syntax within a value read
+// from an external source.
+func importField(s string) string {
+	return strings.NewReplacer("|", "/", "\\", "/", "\n", " ").Replace(strings.TrimSpace(s))
+}
@@ -0,0 +1,427 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// k8sNameLabels are the labels naming the containers of workloads, in order
+// of precedence. Workloads without them are named by their metadata.
+var k8sNameLabels = []string{"app.kubernetes.io/name", "app"}
+
+// k8sWorkloads are the kinds of objects which run pods.
+var k8sWorkloads = []string{"Deployment", "StatefulSet", "DaemonSet"}
+
+// k8sObject is the subset of the Kubernetes objects used by
+// ImportKubernetes. The fields of the specs of all kinds are merged, since
+// they do not collide.
+type k8sObject struct {
+	Kind     string      `yaml:"kind"`
+	Metadata k8sMetadata `yaml:"metadata"`
+	Spec     struct {
+		// Deployment, StatefulSet and DaemonSet
+		Replicas *int `yaml:"replicas"`
+		Template struct {
+			Metadata k8sMetadata `yaml:"metadata"`
+			Spec     struct {
+				Containers []k8sContainer `yaml:"containers"`
+			} `yaml:"spec"`
+		} `yaml:"template"`
+
+		// Service, whose selector is a map of labels, unlike the one
+		// of workloads.
+		Selector interface{} `yaml:"selector"`
+		Ports    []struct {
+			Port int `yaml:"port"`
+		} `yaml:"ports"`
+
+		// Ingress
+		IngressClassName string             `yaml:"ingressClassName"`
+		DefaultBackend   *k8sIngressBackend `yaml:"defaultBackend"`
+		Rules            []struct {
+			Host string `yaml:"host"`
+			HTTP struct {
+				Paths []struct {
+					Path    string            `yaml:"path"`
+					Backend k8sIngressBackend `yaml:"backend"`
+				} `yaml:"paths"`
+			} `yaml:"http"`
+		} `yaml:"rules"`
+
+		// NetworkPolicy
+		PodSelector k8sLabelSelector `yaml:"podSelector"`
+		Ingress     []struct {
+			From []struct {
+				PodSelector *k8sLabelSelector `yaml:"podSelector"`
+			} `yaml:"from"`
+		} `yaml:"ingress"`
+	} `yaml:"spec"`
+	// Items contains the objects of a List.
+	Items []k8sObject `yaml:"items"`
+}
+
+type k8sMetadata struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace"`
+	Labels    map[string]string `yaml:"labels"`
+}
+
+type k8sContainer struct {
+	Image string `yaml:"image"`
+	Env   []struct {
+		Value string `yaml:"value"`
+	} `yaml:"env"`
+}
+
+type k8sLabelSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+// k8sIngressBackend is the backend of an Ingress, either of
+// networking.k8s.io/v1 or of the older extensions/v1beta1.
+type k8sIngressBackend struct {
+	Service struct {
+		Name string `yaml:"name"`
+	} `yaml:"service"`
+	ServiceName string `yaml:"serviceName"`
+}
+
+func (b k8sIngressBackend) service() string {
+	if b.Service.Name != "" {
+		return b.Service.Name
+	}
+	return b.ServiceName
+}
+
+// k8sWorkload is a workload together with the name of its container.
+type k8sWorkload struct {
+	container string
+	namespace string
+	labels    map[string]string
+}
+
+// k8sHostname matches the hostnames of services.
+var k8sHostname = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?$`)
+
+// ImportKubernetes generates the source of the project file at path, which
+// contains a Container of system for every workload of Kubernetes
+// manifests, i.e. Deployments, StatefulSets and DaemonSets, and for every
+// Ingress. Workloads are named by the label app.kubernetes.io/name or app
+// of their pods, so that workloads with the same label become one
+// container. The images of their pods become the technology of the
+// container, their namespace, replicas and kind, and the ports of the
+// Services selecting them, become tags like namespace:shop and replicas:3.
+// Ingresses named like a workload are named <name> ingress.
+//
+// Relationships are inferred from references to Services: from workloads
+// with environment variables containing the hostname of a Service, e.g.
+// http://orders:8080, from Ingresses to the Services of their backends, and
+// from the pods allowed to connect to other pods by NetworkPolicies. They
+// lead to the workloads selected by the Services.
+//
+// The result is merged with the project like the one of ImportCompose.
+func ImportKubernetes(m Model, system, path string, src []byte) ([]byte, error) {
+	objects, err := decodeK8sObjects(src)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := imported{
+		source:      "import k8s",
+		systems:     []System{{Name: system, Tags: []string{}}},
+		tagPrefixes: []string{"namespace:", "replicas:", "workload:", "port:"},
+	}
+	containers := make(map[string]*Container)
+	workloads := make([]k8sWorkload, 0)
+	container := func(name string) *Container {
+		c, ok := containers[name]
+		if !ok {
+			c = &Container{System: system, Name: name, Tags: []string{}}
+			containers[name] = c
+		}
+		return c
+	}
+	addTag := func(c *Container, tag string) {
+		if !contains(c.Tags, tag) {
+			c.Tags = append(c.Tags, tag)
+		}
+	}
+
+	for _, o := range objects {
+		if !contains(k8sWorkloads, o.Kind) {
+			continue
+		}
+		labels := o.Spec.Template.Metadata.Labels
+		w := k8sWorkload{container: k8sName(o), namespace: k8sNamespace(o.Metadata), labels: labels}
+		workloads = append(workloads, w)
+
+		c := container(w.container)
+		images := make([]string, 0)
+		if c.Technology != "" {
+			images = strings.Split(c.Technology, ", ")
+		}
+		for _, pc := range o.Spec.Template.Spec.Containers {
+			if pc.Image != "" && !contains(images, importField(pc.Image)) {
+				images = append(images, importField(pc.Image))
+			}
+		}
+		c.Technology = strings.Join(images, ", ")
+		addTag(c, "namespace:"+w.namespace)
+		addTag(c, "workload:"+o.Kind)
+		if o.Spec.Replicas != nil {
+			addTag(c, "replicas:"+strconv.Itoa(*o.Spec.Replicas))
+		}
+	}
+	if len(workloads) == 0 {
+		return nil, errors.New("no workloads found in Kubernetes manifests")
+	}
+
+	// services contains the containers of the workloads selected by each
+	// service, by namespace and name.
+	services := make(map[[2]string][]string)
+	for _, o := range objects {
+		if o.Kind != "Service" {
+			continue
+		}
+		key := [2]string{k8sNamespace(o.Metadata), o.Metadata.Name}
+		selector := stringMap(o.Spec.Selector)
+		for _, w := range workloads {
+			if w.namespace != key[0] || len(selector) == 0 || !matchLabels(selector, w.labels) {
+				continue
+			}
+			services[key] = append(services[key], w.container)
+			for _, p := range o.Spec.Ports {
+				addTag(container(w.container), "port:"+strconv.Itoa(p.Port))
+			}
+		}
+	}
+
+	rels := make(map[[2]string]Relationship)
+	addRel := func(source, description, technology, destination string) {
+		if source == destination {
+			return
+		}
+		if _, ok := rels[[2]string{source, destination}]; !ok {
+			rels[[2]string{source, destination}] = Relationship{Source: source, Description: description,
+				Technology: technology, Destination: destination, Tags: []string{}}
+		}
+	}
+
+	for _, o := range objects {
+		ns := k8sNamespace(o.Metadata)
+		switch {
+		case contains(k8sWorkloads, o.Kind):
+			for _, pc := range o.Spec.Template.Spec.Containers {
+				for _, env := range pc.Env {
+					for _, ref := range k8sServiceRefs(env.Value, ns, services) {
+						for _, dst := range services[ref.service] {
+							addRel(k8sName(o), "Uses", ref.technology, dst)
+						}
+					}
+				}
+			}
+		case o.Kind == "Ingress":
+			name := importField(o.Metadata.Name)
+			for _, w := range workloads {
+				if w.container == name {
+					// an Ingress is usually named like the workload it routes
+					// to, which must remain a container of its own.
+					name += " ingress"
+					break
+				}
+			}
+			c := container(name)
+			c.Technology = "Ingress"
+			if o.Spec.IngressClassName != "" {
+				c.Technology += " " + importField(o.Spec.IngressClassName)
+			}
+			addTag(c, "namespace:"+ns)
+			if o.Spec.DefaultBackend != nil {
+				for _, dst := range services[[2]string{ns, o.Spec.DefaultBackend.service()}] {
+					addRel(c.Name, "Routes requests", "HTTP", dst)
+				}
+			}
+			for _, rule := range o.Spec.Rules {
+				for _, p := range rule.HTTP.Paths {
+					description := "Routes requests"
+					if rule.Host+p.Path != "" {
+						description = "Routes " + importField(rule.Host+p.Path)
+					}
+					for _, dst := range services[[2]string{ns, p.Backend.service()}] {
+						addRel(c.Name, description, "HTTP", dst)
+					}
+				}
+			}
+		case o.Kind == "NetworkPolicy":
+			for _, ingress := range o.Spec.Ingress {
+				for _, from := range ingress.From {
+					if from.PodSelector == nil {
+						continue
+					}
+					for _, src := range selectWorkloads(workloads, ns, from.PodSelector.MatchLabels) {
+						for _, dst := range selectWorkloads(workloads, ns, o.Spec.PodSelector.MatchLabels) {
+							addRel(src, "Uses", "", dst)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(containers) {
+		imp.containers = append(imp.containers, *containers[name])
+	}
+	keys := make([][2]string, 0, len(rels))
+	for k := range rels {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		imp.relationships = append(imp.relationships, rels[k])
+	}
+	imp.src, err = readImportFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return m.merge(path, imp), nil
+}
+
+// decodeK8sObjects decodes all documents of the manifests, including the
+// items of Lists.
+func decodeK8sObjects(src []byte) ([]k8sObject, error) {
+	objects := make([]k8sObject, 0)
+	dec := yaml.NewDecoder(bytes.NewReader(src))
+	for {
+		var o k8sObject
+		err := dec.Decode(&o)
+		if err == io.EOF {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, o)
+		objects = append(objects, o.Items...)
+	}
+}
+
+// k8sName returns the name of the container of a workload.
+func k8sName(o k8sObject) string {
+	for _, labels := range []map[string]string{o.Spec.Template.Metadata.Labels, o.Metadata.Labels} {
+		for _, key := range k8sNameLabels {
+			if v := labels[key]; v != "" {
+				return importField(v)
+			}
+		}
+	}
+	return importField(o.Metadata.Name)
+}
+
+func k8sNamespace(meta k8sMetadata) string {
+	if meta.Namespace == "" {
+		return "default"
+	}
+	return meta.Namespace
+}
+
+// matchLabels reports whether the labels contain all labels of the
+// selector.
+func matchLabels(selector, labels map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// selectWorkloads returns the containers of the workloads of the namespace
+// matching the selector. An empty selector selects all workloads.
+func selectWorkloads(workloads []k8sWorkload, namespace string, selector map[string]string) []string {
+	names := make([]string, 0)
+	for _, w := range workloads {
+		if w.namespace == namespace && matchLabels(selector, w.labels) {
+			names = append(names, w.container)
+		}
+	}
+	return sortedSet(names)
+}
+
+// A k8sServiceRef is a reference to a Service, together with the
+// technology given by the scheme of its URL, if any.
+type k8sServiceRef struct {
+	service    [2]string
+	technology string
+}
+
+// k8sServiceRefs returns the services whose hostnames occur in the value of
+// an environment variable of a workload in namespace. Services are
+// referenced by their name within the namespace, or by name.namespace,
+// optionally followed by .svc and the cluster domain.
+func k8sServiceRefs(value, namespace string, services map[[2]string][]string) []k8sServiceRef {
+	refs := make([]k8sServiceRef, 0)
+	// values may contain lists of addresses, e.g. of brokers.
+	items := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool { return r == ',' || r == ';' || r == ' ' })
+	for _, item := range items {
+		scheme, host := "", item
+		if i := strings.Index(item, "://"); i >= 0 {
+			// the scheme of jdbc:postgresql://db/x is postgresql.
+			scheme = item[strings.LastIndex(item[:i], ":")+1 : i]
+			host = item[i+3:]
+			if j := strings.IndexAny(host, "/?#"); j >= 0 {
+				host = host[:j]
+			}
+			host = host[strings.LastIndex(host, "@")+1:]
+		}
+		if j := strings.LastIndex(host, ":"); j >= 0 {
+			host = host[:j]
+		}
+		if !k8sHostname.MatchString(host) {
+			continue
+		}
+
+		parts := strings.Split(host, ".")
+		key := [2]string{namespace, parts[0]}
+		if len(parts) > 1 {
+			key[0] = parts[1]
+		}
+		if len(parts) > 2 && parts[2] != "svc" {
+			continue
+		}
+		if _, ok := services[key]; !ok {
+			continue
+		}
+		technology := scheme
+		if scheme == "http" || scheme == "https" {
+			technology = strings.ToUpper(scheme)
+		}
+		refs = append(refs, k8sServiceRef{service: key, technology: technology})
+	}
+	return refs
+}
+
+// stringMap converts a generically decoded map of strings.
+func stringMap(v interface{}) map[string]string {
+	m := make(map[string]string)
+	if v, ok := v.(map[interface{}]interface{}); ok {
+		for k, val := range v {
+			m[fmt.Sprint(k)] = fmt.Sprint(val)
+		}
+	}
+	return m
+}
@@ -0,0 +1,268 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const k8sSource = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web-v2
+  namespace: shop
+spec:
+  replicas: 3
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: web
+        tier: frontend
+    spec:
+      containers:
+        - name: web
+          image: example/web:2.1
+          env:
+            - name: API_URL
+              value: http://api:8080/orders
+            - name: LOG_LEVEL
+              value: info
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: shop
+spec:
+  replicas: 2
+  template:
+    metadata:
+      labels:
+        app: api
+    spec:
+      containers:
+        - name: api
+          image: example/api:1.4
+          env:
+            - name: DATABASE_URL
+              value: postgres://user@db.shop.svc.cluster.local:5432/shop
+        - name: proxy
+          image: envoyproxy/envoy:v1.29
+---
+apiVersion: v1
+kind: List
+items:
+  - apiVersion: apps/v1
+    kind: StatefulSet
+    metadata:
+      name: db
+      namespace: shop
+    spec:
+      replicas: 1
+      template:
+        metadata:
+          labels:
+            app: db
+        spec:
+          containers:
+            - name: postgres
+              image: postgres:16
+  - apiVersion: v1
+    kind: Service
+    metadata:
+      name: db
+      namespace: shop
+    spec:
+      selector:
+        app: db
+      ports:
+        - port: 5432
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+  namespace: shop
+spec:
+  selector:
+    app: api
+  ports:
+    - port: 8080
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+spec:
+  selector:
+    app.kubernetes.io/name: web
+  ports:
+    - port: 80
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: shop-ingress
+  namespace: shop
+spec:
+  ingressClassName: nginx
+  rules:
+    - host: shop.example.com
+      http:
+        paths:
+          - path: /
+            backend:
+              service:
+                name: web
+                port:
+                  number: 80
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: db-access
+  namespace: shop
+spec:
+  podSelector:
+    matchLabels:
+      app: db
+  ingress:
+    - from:
+        - podSelector:
+            matchLabels:
+              tier: frontend
+        - namespaceSelector: {}
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: log-agent
+  namespace: kube-system
+spec:
+  template:
+    metadata:
+      labels:
+        app: log-agent
+    spec:
+      containers:
+        - image: fluent/fluent-bit:3.0
+          env:
+            - name: SHOP_DB
+              value: db
+`
+
+// k8sIngressSource contains an Ingress with the name of the Deployment it
+// routes to.
+const k8sIngressSource = `kind: Deployment
+metadata:
+  name: shop
+spec:
+  template:
+    metadata:
+      labels:
+        app: shop
+    spec:
+      containers:
+        - image: example/shop:1
+---
+kind: Service
+metadata:
+  name: shop
+spec:
+  selector:
+    app: shop
+  ports:
+    - port: 80
+---
+kind: Ingress
+metadata:
+  name: shop
+spec:
+  defaultBackend:
+    service:
+      name: shop
+`
+
+func TestImportKubernetes(t *testing.T) {
+	src, err := ImportKubernetes(*newModel(), "Shop", "", []byte(k8sSource))
+	assertEqual(t, nil, err, "ImportKubernetes returned an error")
+	assertEqual(t, `# Imported by blueprint import k8s.
+# Descriptions and tags edited in this file are kept when importing again.
+
+System = Shop | |
+
+Container = Shop \
+          | api \
+          | \
+          | example/api:1.4, envoyproxy/envoy:v1.29 \
+          | namespace:shop, workload:Deployment, replicas:2, port:8080
+Container = Shop | db           | | postgres:16           | namespace:shop, workload:StatefulSet, replicas:1, port:5432
+Container = Shop | log-agent    | | fluent/fluent-bit:3.0 | namespace:kube-system, workload:DaemonSet
+Container = Shop | shop-ingress | | Ingress nginx         | namespace:shop
+Container = Shop | web          | | example/web:2.1       | namespace:shop, workload:Deployment, replicas:3, port:80
+
+Relationship = api          | Uses                     | postgres | db  |
+Relationship = shop-ingress | Routes shop.example.com/ | HTTP     | web |
+Relationship = web          | Uses                     | HTTP     | api |
+Relationship = web          | Uses                     |          | db  |
+`, string(src), "source does not match")
+
+	src, err = ImportKubernetes(*newModel(), "Shop", "", []byte(k8sIngressSource))
+	assertEqual(t, nil, err, "ImportKubernetes returned an error")
+	assertEqual(t, `# Imported by blueprint import k8s.
+# Descriptions and tags edited in this file are kept when importing again.
+
+System = Shop | |
+
+Container = Shop | shop         | | example/shop:1 | namespace:default, workload:Deployment, port:80
+Container = Shop | shop ingress | | Ingress        | namespace:default
+
+Relationship = shop ingress | Routes requests | HTTP | shop |
+`, string(src), "Ingress named like a workload does not match")
+
+	_, err = ImportKubernetes(*newModel(), "Shop", "", []byte("kind: Service\nmetadata:\n  name: db\n"))
+	assertEqual(t, "no workloads found in Kubernetes manifests", err.Error(), "manifests without workloads must fail")
+}
+
+func TestImportKubernetesMerge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "k8s.c4")
+	err := os.WriteFile(path, []byte("# Deployed by the platform team.\nPersona = Operator | Runs the shop |\n"), 0644)
+	assertEqual(t, nil, err, "WriteFile returned an error")
+
+	src, err := ImportKubernetes(*newModel(), "Shop", path, []byte(k8sIngressSource))
+	assertEqual(t, nil, err, "ImportKubernetes returned an error")
+	assertEqual(t, `# Deployed by the platform team.
+Persona = Operator | Runs the shop |
+
+System = Shop | |
+
+Container = Shop | shop         | | example/shop:1 | namespace:default, workload:Deployment, port:80
+Container = Shop | shop ingress | | Ingress        | namespace:default
+
+Relationship = shop ingress | Routes requests | HTTP | shop |
+`, string(src), "hand-written statements are not kept")
+}
+
+func TestK8sServiceRefs(t *testing.T) {
+	services := map[[2]string][]string{{"shop", "orders"}: {"orders"}, {"shop", "kafka"}: {"kafka"}}
+	for _, tc := range []struct {
+		value string
+		refs  []k8sServiceRef
+	}{
+		{"orders", []k8sServiceRef{{service: [2]string{"shop", "orders"}}}},
+		{"https://orders.shop:8443/api", []k8sServiceRef{{service: [2]string{"shop", "orders"}, technology: "HTTPS"}}},
+		{"jdbc:postgresql://orders.shop.svc/db", []k8sServiceRef{{service: [2]string{"shop", "orders"}, technology: "postgresql"}}},
+		{"kafka:9092,orders:9092", []k8sServiceRef{{service: [2]string{"shop", "kafka"}}, {service: [2]string{"shop", "orders"}}}},
+		{"orders.example.com", []k8sServiceRef{}},
+		{"orders.billing", []k8sServiceRef{}},
+		{"http://api/orders", []k8sServiceRef{}},
+	} {
+		assertEqual(t, tc.refs, k8sServiceRefs(tc.value, "shop", services), tc.value)
+	}
+}
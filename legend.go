@@ -0,0 +1,81 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"html"
+	"strings"
+)
+
+// A legendEntry explains a single kind of element, the boundary or the
+// relationships shown in a graph.
+type legendEntry struct {
+	Kind        string
+	Description string
+	// Color and BorderColor are the colors of element nodes, Style is one
+	// of "element", "boundary" or "relationship".
+	Color       string
+	BorderColor string
+	Style       string
+}
+
+// legendKinds contains the entries of all element kinds, in the order they
+// are listed in legends.
+var legendKinds = []legendEntry{
+	{Kind: "Persona", Description: "A person who uses the software systems", Color: personColor, BorderColor: personBorderColor, Style: "element"},
+	{Kind: "System", Description: "A software system, delivering value to its users", Color: systemColor, BorderColor: systemBorderColor, Style: "element"},
+	{Kind: "Container", Description: "A separately deployed application or data store", Color: containerColor, BorderColor: containerBorderColor, Style: "element"},
+	{Kind: "Component", Description: "A group of related functionality within a container", Color: componentColor, BorderColor: componentBorderColor, Style: "element"},
+}
+
+// legend returns the legend of the graph, which only explains the element
+// kinds and lines which are actually used in it.
+func (g graph) legend() []legendEntry {
+	kinds := make(map[string]bool)
+	for _, nodes := range [][]node{g.CoreNodes, g.TopNodes, g.BottomNodes} {
+		for _, n := range nodes {
+			kinds[n.Kind] = true
+		}
+	}
+
+	entries := make([]legendEntry, 0)
+	for _, e := range legendKinds {
+		if kinds[e.Kind] {
+			entries = append(entries, e)
+		}
+	}
+	if len(g.CoreNodes) > 0 && g.Boundary != "" {
+		entries = append(entries, legendEntry{Kind: "Boundary", Description: g.Boundary, Style: "boundary"})
+	}
+	if len(g.Edges) > 0 {
+		entries = append(entries, legendEntry{Kind: "Relationship",
+			Description: "The source uses the destination, labelled with description and [technology]",
+			Style:       "relationship"})
+	}
+	return entries
+}
+
+// LegendLabel returns the HTML-like graphviz label of the legend node.
+func (g graph) LegendLabel() string {
+	var b strings.Builder
+	b.WriteString(`<TABLE BORDER="0" CELLSPACING="4" CELLPADDING="4">`)
+	for _, e := range g.Legend {
+		b.WriteString("<TR>")
+		switch e.Style {
+		case "element":
+			b.WriteString(`<TD BGCOLOR="` + e.Color + `" COLOR="` + e.BorderColor + `" BORDER="1">`)
+			b.WriteString(`<FONT COLOR="white">` + e.Kind + `</FONT></TD>`)
+		case "boundary":
+			b.WriteString(`<TD COLOR="#7b7b7b" BORDER="1" STYLE="dashed">` + e.Kind + `</TD>`)
+		default:
+			b.WriteString(`<TD><FONT COLOR="dimgrey">` + e.Kind + ` &#8594;</FONT></TD>`)
+		}
+		b.WriteString(`<TD ALIGN="LEFT">` + html.EscapeString(e.Description) + `</TD>`)
+		b.WriteString("</TR>")
+	}
+	b.WriteString("</TABLE>")
+	return b.String()
+}
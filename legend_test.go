@@ -0,0 +1,42 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGraphLegend(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	view, _ := m.View("components/Web App")
+	kinds := make([]string, 0)
+	for _, e := range viewGraph(view, m).Legend {
+		kinds = append(kinds, e.Kind)
+	}
+	assertEqual(t, []string{"Container", "Component", "Boundary", "Relationship"}, kinds,
+		"legend does not match the elements of the view")
+}
+
+func TestGraphLegendOption(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+	view, _ := m.View("contexts/index")
+
+	buf := new(bytes.Buffer)
+	err = RenderDOT(buf, view, m)
+	assertEqual(t, nil, err, "RenderDOT returned an error")
+	assertEqual(t, true, strings.Contains(buf.String(), "subgraph cluster_legend"), "legend expected")
+
+	m.ViewOptions["contexts/index"] = ViewOptions{View: "contexts/index", Options: map[string]string{"legend": "false"}}
+	buf.Reset()
+	err = RenderDOT(buf, view, m)
+	assertEqual(t, nil, err, "RenderDOT returned an error")
+	assertEqual(t, false, strings.Contains(buf.String(), "subgraph cluster_legend"), "legend not expected")
+}
@@ -5,15 +5,44 @@
 
 package blueprint
 
+import (
+	"encoding/json"
+	"sort"
+)
+
 // Model is the C4 architecture model representation of a project.
+//
+// A Model is never modified after parsing. Its methods and the render
+// functions of this package only read it, so it is safe for concurrent use
+// by multiple goroutines as long as its fields are not modified.
 type Model struct {
-	Personas       map[string]Persona
-	SystemContexts map[string]SystemContext
-	Systems        map[string]System
-	Containers     map[string]Container
-	Components     map[string]Component
-	Relationships  []Relationship
-	Errors         []error
+	Personas       map[string]Persona       `json:"personas"`
+	SystemContexts map[string]SystemContext `json:"systemContexts"`
+	Systems        map[string]System        `json:"systems"`
+	Containers     map[string]Container     `json:"containers"`
+	Components     map[string]Component     `json:"components"`
+	Relationships  []Relationship           `json:"relationships"`
+	ViewOptions    map[string]ViewOptions   `json:"viewOptions"`
+	LayoutHints    []LayoutHint             `json:"layoutHints"`
+	Rules          []Rule                   `json:"rules"`
+	GoPackages     map[string]GoPackage     `json:"goPackages"`
+	APISpecs       map[string]APISpec       `json:"apiSpecs"`
+	Errors         []error                  `json:"errors"`
+	// Warnings point to likely mistakes, which do not make the model
+	// incorrect, e.g. elements without any relationship.
+	Warnings []error `json:"warnings"`
+
+	// positions contains the locations of all element definitions by
+	// "Kind:Name", relPositions the locations of all Relationships,
+	// hintPositions the locations of all LayoutHints and rulePositions the
+	// locations of all Rules.
+	positions     map[string]position
+	relPositions  []position
+	hintPositions []position
+	rulePositions []position
+	// violations contains the names of the elements and the keys of the
+	// relationships which violate Rules.
+	violations map[string]bool
 }
 
 func newModel() *Model {
@@ -24,58 +53,206 @@ func newModel() *Model {
 	m.Containers = make(map[string]Container)
 	m.Components = make(map[string]Component)
 	m.Relationships = make([]Relationship, 0)
+	m.ViewOptions = make(map[string]ViewOptions)
+	m.LayoutHints = make([]LayoutHint, 0)
+	m.GoPackages = make(map[string]GoPackage)
+	m.APISpecs = make(map[string]APISpec)
 	m.Errors = make([]error, 0)
+	m.Warnings = make([]error, 0)
+	m.positions = make(map[string]position)
+	m.relPositions = make([]position, 0)
+	m.hintPositions = make([]position, 0)
+	m.rulePositions = make([]position, 0)
+	m.violations = make(map[string]bool)
 	return m
 }
 
 // A Persona that interacts with other entities of the software system.
 type Persona struct {
-	Name        string
-	Description string
-	Tags        []string
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
 }
 
 // A SystemContext defines a subset of Systems of the whole project that
 // interact with each other.
 type SystemContext struct {
-	Name            string
-	Description     string
-	CoreSystems     []string
-	ExternalSystems []string
+	Name            string   `json:"name"`
+	Description     string   `json:"description"`
+	CoreSystems     []string `json:"coreSystems"`
+	ExternalSystems []string `json:"externalSystems"`
 }
 
 // A System according to the C4 software architecture model.
 type System struct {
-	Name        string
-	Description string
-	Tags        []string
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
 }
 
 // A Container according to the C4 software architecture model.
 type Container struct {
-	System      string
-	Name        string
-	Description string
-	Technology  string
-	Tags        []string
+	System      string   `json:"system"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Technology  string   `json:"technology"`
+	Tags        []string `json:"tags"`
 }
 
 // A Component according to the C4 software architecture model.
 type Component struct {
-	Container   string
-	Name        string
-	Description string
-	Technology  string
-	Tags        []string
+	Container   string   `json:"container"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Technology  string   `json:"technology"`
+	Tags        []string `json:"tags"`
 }
 
 // A Relationship between two arbitrary entities of the C4 model.
 type Relationship struct {
-	Source      string
-	Description string
-	Technology  string
-	Destination string
-	Tags        []string
+	Source      string   `json:"source"`
+	Description string   `json:"description"`
+	Technology  string   `json:"technology"`
+	Destination string   `json:"destination"`
+	Tags        []string `json:"tags"`
+	// Operations are the operations of the API of the destination used by
+	// the relationship, as referenced by its api: tags.
+	Operations []APIOperation `json:"operations,omitempty"`
+}
+
+// ViewOptions configure how a view, identified by its ID, is rendered. If
+// View is empty, the options apply to all views of the project. Options of a
+// single view take precedence over the ones of the project.
+type ViewOptions struct {
+	View    string            `json:"view"`
+	Options map[string]string `json:"options"`
+}
+
+// A LayoutHint guides graphviz when laying out a view, identified by its ID,
+// or all views of the project if View is empty. Kind is one of:
+//
+//   - SameRank: all Elements are placed on the same rank
+//   - Above: the first of the Elements is placed above the second one
+//   - Pin: the element is pinned to the top or bottom rank, see Position
+//   - EdgeOptions: the graphviz Options of the relationships from the
+//     first of the Elements to the second one
+//
+// Hints referring to elements which are not shown in a view are ignored
+// for that view.
+type LayoutHint struct {
+	Kind     string            `json:"kind"`
+	View     string            `json:"view"`
+	Elements []string          `json:"elements"`
+	Position string            `json:"position,omitempty"`
+	Options  map[string]string `json:"options,omitempty"`
+}
+
+// A Rule is an architecture constraint, which is checked against the model.
+// Violations are reported as errors. Kind is one of:
+//
+//   - DenyRelationship: no relationships from elements selected by the first
+//     of the Args to elements selected by the second one
+//   - ContainerBoundary: no relationships from components to components or
+//     containers of other containers, except to elements selected by the
+//     first of the Args
+//   - NoCycles: no dependency cycles between elements of the kind given by
+//     the first of the Args, System, Container or Component, including the
+//     relationships of all elements within them
+//   - RequireTag: all elements selected by the first of the Args have the
+//     tag given by the second one, which may end with * to match any suffix
+//
+// Selectors are either the name of an element, * for all elements,
+// tag:Tag for all elements with a tag or kind:Kind for all elements of a
+// kind, where kind:ExternalSystem selects systems which are only external
+// systems of system contexts.
+type Rule struct {
+	Kind string   `json:"kind"`
+	Args []string `json:"args"`
+}
+
+// A GoPackage maps a Component to the Go packages implementing it. Patterns
+// are package paths, in which ... matches any string, e.g.
+// example.com/shop/cart/... for all packages below the cart package.
+type GoPackage struct {
+	Component string   `json:"component"`
+	Patterns  []string `json:"patterns"`
+}
+
+// An APISpec attaches OpenAPI, Swagger or AsyncAPI specifications to a
+// Container. Files are relative to the project file defining the APISpec,
+// APIs contains the specifications which could be loaded.
+type APISpec struct {
+	Container string   `json:"container"`
+	Files     []string `json:"files"`
+	APIs      []API    `json:"apis"`
+}
+
+// An API is the interface of a container described by a single
+// specification file, e.g. OpenAPI 3.0.3.
+type API struct {
+	File          string         `json:"file"`
+	Specification string         `json:"specification"`
+	Title         string         `json:"title"`
+	Version       string         `json:"version"`
+	Operations    []APIOperation `json:"operations"`
+}
+
+// An APIOperation is an operation of an API. Method is the HTTP method of
+// OpenAPI operations, or the action of AsyncAPI operations like publish or
+// send, Path the path or channel they operate on.
+type APIOperation struct {
+	ID      string `json:"id"`
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Summary string `json:"summary"`
+}
+
+func (op APIOperation) String() string {
+	return op.Method + " " + op.Path
+}
+
+// MarshalJSON encodes the model as JSON. Errors and warnings are encoded as
+// objects containing their message and, if known, their source location.
+func (m Model) MarshalJSON() ([]byte, error) {
+	type model Model
+	return json.Marshal(struct {
+		model
+		Errors   []ErrorJSON `json:"errors"`
+		Warnings []ErrorJSON `json:"warnings"`
+	}{model(m), ErrorsJSON(m.Errors), ErrorsJSON(m.Warnings)})
+}
+
+// Diagnostics returns all errors of the model followed by all of its
+// warnings.
+func (m Model) Diagnostics() []error {
+	diags := make([]error, 0, len(m.Errors)+len(m.Warnings))
+	diags = append(diags, m.Errors...)
+	return append(diags, m.Warnings...)
+}
+
+// ErrorJSON is the JSON representation of a model error.
+type ErrorJSON struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+}
+
+// ErrorsJSON converts errors to their JSON representation.
+func ErrorsJSON(errs []error) []ErrorJSON {
+	result := make([]ErrorJSON, 0, len(errs))
+	for _, err := range errs {
+		severity := "error"
+		if IsWarning(err) {
+			severity = "warning"
+		}
+		if pErr, ok := err.(parseError); ok {
+			result = append(result, ErrorJSON{File: pErr.File, Line: pErr.Line, Message: pErr.Msg, Severity: severity})
+		} else {
+			result = append(result, ErrorJSON{Message: err.Error(), Severity: severity})
+		}
+	}
+	return result
 }
 
 // FindRelationships searches for relationships which are relevant for a given
@@ -95,3 +272,32 @@ func (m Model) FindRelationships(nodes []string) []Relationship {
 	}
 	return rels
 }
+
+// sortedKeys returns the keys of an element map in lexical order, which is
+// used wherever the model needs to be traversed deterministically.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Element looks up the element with the given name and returns its kind
+// (Persona, System, Container or Component) together with the element.
+func (m Model) Element(name string) (string, interface{}, bool) {
+	if p, ok := m.Personas[name]; ok {
+		return "Persona", p, true
+	}
+	if s, ok := m.Systems[name]; ok {
+		return "System", s, true
+	}
+	if c, ok := m.Containers[name]; ok {
+		return "Container", c, true
+	}
+	if c, ok := m.Components[name]; ok {
+		return "Component", c, true
+	}
+	return "", nil, false
+}
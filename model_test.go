@@ -0,0 +1,35 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestModelMarshalJSON(t *testing.T) {
+	m := newModel()
+	parseSystem(m, "test/sys.c4", 1, "Test System | Test Description | tag1")
+	parseSystem(m, "test/sys.c4", 2, "Test System | Test Description | tag1")
+	m.Errors = append(m.Errors, errors.New("other error"))
+
+	data, err := json.Marshal(m)
+	assertEqual(t, nil, err, "MarshalJSON returned an error")
+
+	var decoded struct {
+		Systems map[string]map[string]interface{}
+		Errors  []ErrorJSON
+	}
+	err = json.Unmarshal(data, &decoded)
+	assertEqual(t, nil, err, "Unmarshal returned an error")
+	assertEqual(t, "Test Description", decoded.Systems["Test System"]["description"], "system description does not match")
+	expectedErrs := []ErrorJSON{
+		{File: "test/sys.c4", Line: 2, Message: "System is already defined: Test System", Severity: "error"},
+		{Message: "other error", Severity: "error"},
+	}
+	assertEqual(t, expectedErrs, decoded.Errors, "errors do not match")
+}
@@ -6,6 +6,7 @@
 package blueprint
 
 import (
+	"html"
 	"net/url"
 )
 
@@ -31,7 +32,7 @@ func systemNode(s System) node {
 		"color":     systemBorderColor,
 		"URL":       "../containers/" + url.PathEscape(s.Name) + ".html",
 	}
-	return node{Name: s.Name, Attrs: attrs}
+	return node{Name: s.Name, Kind: "System", Attrs: attrs}
 }
 
 func containerNode(c Container) node {
@@ -43,7 +44,7 @@ func containerNode(c Container) node {
 		"color":     containerBorderColor,
 		"URL":       "../components/" + url.PathEscape(c.Name) + ".html",
 	}
-	return node{Name: c.Name, Attrs: attrs}
+	return node{Name: c.Name, Kind: "Container", Attrs: attrs}
 }
 
 func componentNode(c Component) node {
@@ -54,7 +55,7 @@ func componentNode(c Component) node {
 		"fillcolor": componentColor,
 		"color":     componentBorderColor,
 	}
-	return node{Name: c.Name, Attrs: attrs}
+	return node{Name: c.Name, Kind: "Component", Attrs: attrs}
 }
 
 func personaNode(p Persona) node {
@@ -65,14 +66,14 @@ func personaNode(p Persona) node {
 		"fillcolor": personColor,
 		"color":     personBorderColor,
 	}
-	return node{Name: p.Name, Attrs: attrs}
+	return node{Name: p.Name, Kind: "Persona", Attrs: attrs}
 }
 
 func relationshipEdge(r Relationship) edge {
 	attrs := map[string]string{
-		"label": "<TABLE BORDER=\"0\"><TR><TD>" + wrapWords(r.Description, lineLimit) + edgeTechnology(r) + "</TD></TR></TABLE>",
+		"label": "<TABLE BORDER=\"0\"><TR><TD>" + wrapWords(r.Description, lineLimit) + edgeTechnology(r) + edgeOperations(r) + "</TD></TR></TABLE>",
 	}
-	return edge{Source: r.Source, Destination: r.Destination, Attrs: attrs}
+	return edge{Source: r.Source, Destination: r.Destination, Attrs: attrs, rel: &r}
 }
 
 func edgeTechnology(r Relationship) string {
@@ -82,6 +83,22 @@ func edgeTechnology(r Relationship) string {
 	return "<BR/>[" + wrapWords(r.Technology, lineLimit) + "]"
 }
 
+// edgeOperations lists the operations of the API of the destination used by
+// the relationship, one per line.
+func edgeOperations(r Relationship) string {
+	if len(r.Operations) == 0 {
+		return ""
+	}
+	label := "<BR/><FONT POINT-SIZE=\"9\">"
+	for i, op := range r.Operations {
+		if i > 0 {
+			label += "<BR/>"
+		}
+		label += html.EscapeString(op.String())
+	}
+	return label + "</FONT>"
+}
+
 func nodeTechnology(nodeKind, technology string) string {
 	if technology == "" {
 		return "[" + nodeKind + "]"
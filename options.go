@@ -0,0 +1,118 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// viewOptions contains all known view options, together with a function
+// which reports whether a value of the option is valid.
+var viewOptions = map[string]func(string) bool{
+	"legend":   oneOf("true", "false"),
+	"rankdir":  oneOf("TB", "LR", "BT", "RL"),
+	"ranksep":  isPositive,
+	"nodesep":  isPositive,
+	"splines":  oneOf("ortho", "polyline", "curved", "spline", "line"),
+	"nodesize": oneOf("small", "medium", "large"),
+}
+
+// layoutOptions are the view options which are passed to graphviz.
+var layoutOptions = []string{"rankdir", "ranksep", "nodesep", "splines", "nodesize"}
+
+// defaultLayout contains the values of the layout options which are used if
+// they are not set. graphviz defaults are used for options without a value.
+var defaultLayout = map[string]string{
+	"ranksep":  "1.0",
+	"nodesep":  "1.0",
+	"nodesize": "medium",
+}
+
+// A nodeSize is a preset of the graphviz attributes which define the size of
+// nodes. Width and Height are minimum sizes in inches.
+type nodeSize struct {
+	Margin string
+	Width  string
+	Height string
+}
+
+var nodeSizes = map[string]nodeSize{
+	"small":  {Margin: "0.10,0.05"},
+	"medium": {Margin: "0.20,0.20"},
+	"large":  {Margin: "0.30,0.30", Width: "3.5", Height: "2.0"},
+}
+
+func oneOf(values ...string) func(string) bool {
+	return func(v string) bool {
+		for _, value := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func isPositive(v string) bool {
+	f, err := strconv.ParseFloat(v, 64)
+	return err == nil && f > 0 && !math.IsInf(f, 1)
+}
+
+// viewOption returns the value of an option of the view. Options of the view
+// itself take precedence over the options of the project.
+func (m Model) viewOption(view View, key string) (string, bool) {
+	if v, ok := m.ViewOptions[view.ID()].Options[key]; ok {
+		return v, true
+	}
+	v, ok := m.ViewOptions[""].Options[key]
+	return v, ok
+}
+
+func parseViewOptions(m *Model, path string, lineno int, value string) {
+	fields := strings.Split(value, "|")
+	if len(fields) < 2 {
+		m.addErr(path, lineno, "ViewOptions requires 2 elements: View | Options")
+		return
+	}
+	if len(fields) > 2 {
+		m.addErr(path, lineno, "ViewOptions requires 2 elements: View | Options")
+	}
+
+	view := strings.TrimSpace(fields[0])
+	options := make(map[string]string)
+	for _, opt := range nonEmpty(parseTags(fields[1])) {
+		i := strings.Index(opt, "=")
+		if i == -1 {
+			m.addErr(path, lineno, "view option requires a value: "+opt)
+			continue
+		}
+		key := strings.TrimSpace(opt[:i])
+		val := strings.TrimSpace(opt[i+1:])
+		valid, ok := viewOptions[key]
+		if !ok {
+			m.addErr(path, lineno, "unknown view option: "+key)
+			continue
+		}
+		if !valid(val) {
+			m.addErr(path, lineno, "invalid value of view option "+key+": "+val)
+			continue
+		}
+		options[key] = val
+	}
+
+	if _, ok := m.ViewOptions[view]; ok {
+		if view == "" {
+			m.addErr(path, lineno, "ViewOptions are already defined for the project")
+		} else {
+			m.addErr(path, lineno, "ViewOptions are already defined for view: "+view)
+		}
+		return
+	}
+	m.define("ViewOptions", view, path, lineno)
+	m.ViewOptions[view] = ViewOptions{View: view, Options: options}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseViewOptions(t *testing.T) {
+	src := `System = Sys | |
+ViewOptions = | legend=false
+ViewOptions = containers/Sys | legend = true
+ViewOptions = containers/Sys | legend=true
+ViewOptions = containers/Other | legend=true
+ViewOptions = contexts/index | legend, color=red, legend=maybe
+`
+	m, err := ParseReader("test.c4", strings.NewReader(src))
+	assertEqual(t, nil, err, "ParseReader returned an error")
+
+	assertEqual(t, ViewOptions{View: "", Options: map[string]string{"legend": "false"}}, m.ViewOptions[""],
+		"project options do not match")
+	assertEqual(t, ViewOptions{View: "containers/Sys", Options: map[string]string{"legend": "true"}}, m.ViewOptions["containers/Sys"],
+		"view options do not match")
+
+	errs := make([]string, 0)
+	for _, err := range m.Errors {
+		if !IsWarning(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+	assertEqual(t, []string{
+		"test.c4:4: ViewOptions are already defined for view: containers/Sys",
+		"test.c4:6: view option requires a value: legend",
+		"test.c4:6: unknown view option: color",
+		"test.c4:6: invalid value of view option legend: maybe",
+		"test.c4:5: View of ViewOptions is not defined: containers/Other",
+	}, errs, "errors do not match")
+}
+
+func TestViewOption(t *testing.T) {
+	m := newModel()
+	m.Systems["Sys"] = System{Name: "Sys"}
+	view := m.NewContainerView(m.Systems["Sys"])
+
+	_, ok := m.viewOption(view, "legend")
+	assertEqual(t, false, ok, "option without definition")
+
+	m.ViewOptions[""] = ViewOptions{Options: map[string]string{"legend": "false"}}
+	v, _ := m.viewOption(view, "legend")
+	assertEqual(t, "false", v, "project option expected")
+
+	m.ViewOptions["containers/Sys"] = ViewOptions{View: "containers/Sys", Options: map[string]string{"legend": "true"}}
+	v, _ = m.viewOption(view, "legend")
+	assertEqual(t, "true", v, "view option expected")
+}
+
+func TestLayoutOptions(t *testing.T) {
+	src := `System = Sys | |
+System = Ext | |
+Relationship = Sys | Uses | | Ext |
+ViewOptions = | rankdir=LR, ranksep=0.5
+ViewOptions = containers/Sys | splines=ortho, nodesize=large, ranksep=2
+ViewOptions = containers/Ext | ranksep=-1, rankdir=up
+`
+	m, err := ParseReader("test.c4", strings.NewReader(src))
+	assertEqual(t, nil, err, "ParseReader returned an error")
+	assertEqual(t, "test.c4:6: invalid value of view option ranksep: -1", m.Errors[0].Error(), "")
+	assertEqual(t, "test.c4:6: invalid value of view option rankdir: up", m.Errors[1].Error(), "")
+
+	view, _ := m.View("contexts/index")
+	buf := new(bytes.Buffer)
+	err = RenderDOT(buf, view, m)
+	assertEqual(t, nil, err, "RenderDOT returned an error")
+	dot := buf.String()
+	assertEqual(t, true, strings.Contains(dot, `ranksep="0.5";`), "project ranksep expected")
+	assertEqual(t, true, strings.Contains(dot, `rankdir="LR";`), "project rankdir expected")
+	assertEqual(t, false, strings.Contains(dot, `splines=`), "splines not expected")
+
+	view, _ = m.View("containers/Sys")
+	graph := viewGraph(view, m)
+	assertEqual(t, "2", graph.LayoutOption("ranksep"), "view ranksep expected")
+	assertEqual(t, "LR", graph.LayoutOption("rankdir"), "project rankdir expected")
+	assertEqual(t, nodeSizes["large"], graph.NodeSize(), "node size does not match")
+}
+
+func TestLayoutOrthoLabels(t *testing.T) {
+	m := newModel()
+	m.Systems["A"] = System{Name: "A"}
+	m.Systems["B"] = System{Name: "B"}
+	m.Relationships = append(m.Relationships, Relationship{Source: "A", Description: "Uses", Destination: "B"})
+	m.ViewOptions[""] = ViewOptions{Options: map[string]string{"splines": "ortho"}}
+
+	g := viewGraph(m.NewGenericSystemContextView(), *m)
+	assertEqual(t, 1, len(g.Edges), "1 edge expected")
+	_, ok := g.Edges[0].Attrs["label"]
+	assertEqual(t, false, ok, "label not expected for orthogonal edges")
+	assertEqual(t, true, strings.Contains(g.Edges[0].Attrs["xlabel"], "Uses"), "xlabel expected")
+}
@@ -7,7 +7,10 @@ package blueprint
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,31 +20,113 @@ type parseError struct {
 	Msg  string
 	File string
 	Line int
+	// Warning marks errors which do not make the model incorrect, but
+	// point to a likely mistake.
+	Warning bool
 }
 
 func (e parseError) Error() string {
+	if e.Warning {
+		return fmt.Sprintf("%s:%d: warning: %s", e.File, e.Line, e.Msg)
+	}
 	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
 }
 
+// IsWarning reports whether err is a warning about the model rather than an
+// actual error.
+func IsWarning(err error) bool {
+	pErr, ok := err.(parseError)
+	return ok && pErr.Warning
+}
+
+// position is the location of an element definition.
+type position struct {
+	File string
+	Line int
+}
+
 // Parse all the files located recursively in path and return the resulting
 // model. An error is returned if parsing could not be resumed. "Soft" errors
 // which only cause a maybe incorrect model, which can still represented
 // containing erroneus entities or relationships, (e.g. a duplicate definition
-// of the same entity) are stored in the model.
+// of the same entity) are stored in the model. Files ending in .yaml, .yml or
+// .json are skipped, whether they are referenced by an APISpec or not.
 func Parse(path string) (Model, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return *newModel(), err
+	}
+	if !info.IsDir() {
+		f, err := os.Open(path)
+		if err != nil {
+			return *newModel(), err
+		}
+		defer f.Close()
+		return ParseReader(path, f)
+	}
+
+	return parseFS(os.DirFS(path), ".", func(name string) string {
+		return filepath.Join(path, filepath.FromSlash(name))
+	}, readSpecFile)
+}
+
+// ParseFS parses all the files located recursively in the directory root of
+// the file system fsys, just like Parse does for the local file system.
+// Errors refer to the files by their path within fsys.
+func ParseFS(fsys fs.FS, root string) (Model, error) {
+	return parseFS(fsys, root, func(name string) string {
+		return name
+	}, nil)
+}
+
+// ParseReader parses the content of a single file read from r. The name is
+// used to refer to the file in errors, and to locate the specification files
+// of APISpecs on the local file system.
+func ParseReader(name string, r io.Reader) (Model, error) {
+	m := newModel()
+	err := parseReader(name, r, m)
+	if err != nil {
+		return *m, err
+	}
+
+	m.loadAPISpecs(readSpecFile)
+	m.validate()
+	return *m, nil
+}
+
+// parseFS parses all files of fsys located in root, except for API
+// specifications. fileName converts the paths of fsys to the file names used
+// in errors. The specifications referenced by APISpecs are read by read, or
+// from fsys if read is nil.
+func parseFS(fsys fs.FS, root string, fileName func(string) string, read specReader) (Model, error) {
 	m := newModel()
-	err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+	paths := make(map[string]string)
+	err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if info.IsDir() {
+		if d.IsDir() || isSpecFile(path) {
 			return nil
+		}
+		paths[fileName(path)] = path
 
+		f, err := fsys.Open(path)
+		if err != nil {
+			return err
 		}
-		return parseFile(path, m)
+		defer f.Close()
+		return parseReader(fileName(path), f, m)
 	})
+	if err != nil {
+		return *m, err
+	}
 
-	return *m, err
+	if read == nil {
+		read = fsSpecReader(fsys, paths)
+	}
+	m.loadAPISpecs(read)
+	m.validate()
+	return *m, nil
 }
 
 func parseLine(s *bufio.Scanner) (string, int) {
@@ -65,15 +150,17 @@ func parseLine(s *bufio.Scanner) (string, int) {
 	return text, lineCnt
 }
 
-func parseFile(path string, m *Model) error {
-	file, err := os.Open(path)
-	if err != nil {
-		return err
-	}
+// maxLineLength limits the length of the lines of project files. Longer
+// lines are reported as error of their file, rather than failing the parsing
+// of the whole project.
+const maxLineLength = 16 << 20
 
+func parseReader(path string, r io.Reader, m *Model) error {
 	lineno := 0
-	s := bufio.NewScanner(file)
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxLineLength)
 	for s.Scan() {
+		lineno++
 		line, lineCnt := parseLine(s)
 		if line == "" {
 			lineno += lineCnt - 1
@@ -87,22 +174,7 @@ func parseFile(path string, m *Model) error {
 		}
 		key := strings.TrimSpace(line[:i])
 		value := strings.TrimSpace(line[i+1:])
-		switch key {
-		case "Persona", "Person":
-			parsePersona(m, path, lineno, value)
-		case "System", "SoftwareSystem":
-			parseSystem(m, path, lineno, value)
-		case "Container":
-			parseContainer(m, path, lineno, value)
-		case "Component":
-			parseComponent(m, path, lineno, value)
-		case "Relationship":
-			parseRelationship(m, path, lineno, value)
-		case "SystemContext":
-			parseSystemContext(m, path, lineno, value)
-		default:
-			m.addErr(path, lineno, "unknown keyword: "+key)
-		}
+		parseElement(m, path, lineno, key, value)
 
 		// lineno in error messages should always point to the first
 		// line of an element. Therefore we add line count of
@@ -110,7 +182,63 @@ func parseFile(path string, m *Model) error {
 		lineno += lineCnt - 1
 	}
 
-	return nil
+	if errors.Is(s.Err(), bufio.ErrTooLong) {
+		m.addErr(path, lineno+1, fmt.Sprintf("line exceeds the maximum length of %d bytes", maxLineLength))
+		return nil
+	}
+	return s.Err()
+}
+
+// ParseSyntaxFiles returns the model of a project consisting of the given
+// files. Just like Parse, it stores "soft" errors in the model.
+func ParseSyntaxFiles(files ...*SyntaxFile) Model {
+	m := newModel()
+	for _, f := range files {
+		for _, stmt := range f.Stmts {
+			if stmt.Kind != ElementStmt {
+				continue
+			}
+			parseElement(m, f.Name, stmt.Start.Line, stmt.Keyword.Text, stmt.Value())
+		}
+	}
+	m.loadAPISpecs(readSpecFile)
+	m.validate()
+	return *m
+}
+
+func parseElement(m *Model, path string, lineno int, key, value string) {
+	switch key {
+	case "Persona", "Person":
+		parsePersona(m, path, lineno, value)
+	case "System", "SoftwareSystem":
+		parseSystem(m, path, lineno, value)
+	case "Container":
+		parseContainer(m, path, lineno, value)
+	case "Component":
+		parseComponent(m, path, lineno, value)
+	case "Relationship":
+		parseRelationship(m, path, lineno, value)
+	case "SystemContext":
+		parseSystemContext(m, path, lineno, value)
+	case "ViewOptions":
+		parseViewOptions(m, path, lineno, value)
+	case "SameRank":
+		parseSameRank(m, path, lineno, value)
+	case "Above":
+		parseAbove(m, path, lineno, value)
+	case "Pin":
+		parsePin(m, path, lineno, value)
+	case "EdgeOptions":
+		parseEdgeOptions(m, path, lineno, value)
+	case "DenyRelationship", "ContainerBoundary", "NoCycles", "RequireTag":
+		parseRule(m, path, lineno, key, value)
+	case "GoPackage":
+		parseGoPackage(m, path, lineno, value)
+	case "APISpec":
+		parseAPISpec(m, path, lineno, value)
+	default:
+		m.addErr(path, lineno, "unknown keyword: "+key)
+	}
 }
 
 func parsePersona(m *Model, path string, lineno int, value string) {
@@ -131,6 +259,7 @@ func parsePersona(m *Model, path string, lineno int, value string) {
 		m.addErr(path, lineno, "Persona is already defined: "+name)
 		return
 	}
+	m.define("Persona", name, path, lineno)
 	m.Personas[name] = Persona{Name: name, Description: description, Tags: tags}
 }
 
@@ -152,6 +281,7 @@ func parseSystem(m *Model, path string, lineno int, value string) {
 		m.addErr(path, lineno, "System is already defined: "+name)
 		return
 	}
+	m.define("System", name, path, lineno)
 	m.Systems[name] = System{Name: name, Description: description, Tags: tags}
 }
 
@@ -175,6 +305,7 @@ func parseContainer(m *Model, path string, lineno int, value string) {
 		m.addErr(path, lineno, "Container is already defined: "+name)
 		return
 	}
+	m.define("Container", name, path, lineno)
 	m.Containers[name] = Container{Name: name, System: system, Description: description, Technology: technology, Tags: tags}
 }
 
@@ -198,6 +329,7 @@ func parseComponent(m *Model, path string, lineno int, value string) {
 		m.addErr(path, lineno, "Component is already defined: "+name)
 		return
 	}
+	m.define("Component", name, path, lineno)
 	m.Components[name] = Component{Name: name, Container: container, Description: description, Technology: technology, Tags: tags}
 }
 
@@ -217,6 +349,7 @@ func parseRelationship(m *Model, path string, lineno int, value string) {
 	destination := strings.TrimSpace(fields[3])
 	tags := parseTags(fields[4])
 
+	m.relPositions = append(m.relPositions, position{File: path, Line: lineno})
 	m.Relationships = append(m.Relationships,
 		Relationship{Source: source, Description: description, Technology: technology, Destination: destination, Tags: tags})
 }
@@ -236,10 +369,15 @@ func parseSystemContext(m *Model, path string, lineno int, value string) {
 	name := strings.TrimSpace(fields[2])
 	description := strings.TrimSpace(fields[3])
 
+	if name == genericContext {
+		m.addErr(path, lineno, "SystemContext name is reserved for the generic system context: "+name)
+		return
+	}
 	if _, ok := m.SystemContexts[name]; ok {
 		m.addErr(path, lineno, "View is already defined: "+name)
 		return
 	}
+	m.define("SystemContext", name, path, lineno)
 	m.SystemContexts[name] = SystemContext{Name: name, Description: description, CoreSystems: coreSys, ExternalSystems: extSys}
 }
 
@@ -255,3 +393,12 @@ func (m *Model) addErr(path string, lineno int, msg string) {
 	err := parseError{File: path, Line: lineno, Msg: msg}
 	m.Errors = append(m.Errors, err)
 }
+
+func (m *Model) addWarn(path string, lineno int, msg string) {
+	err := parseError{File: path, Line: lineno, Msg: msg, Warning: true}
+	m.Warnings = append(m.Warnings, err)
+}
+
+func (m *Model) define(kind, name, path string, lineno int) {
+	m.positions[kind+":"+name] = position{File: path, Line: lineno}
+}
@@ -8,9 +8,11 @@ package blueprint
 import (
 	"bufio"
 	"fmt"
+	"os"
 	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestParseSystem(t *testing.T) {
@@ -60,6 +62,18 @@ func TestParseSystemDuplicate(t *testing.T) {
 	assertEqual(t, sys, expectedSys, "system content does not match")
 }
 
+func TestParseSystemContextReserved(t *testing.T) {
+	m := newModel()
+	path := "test/parsesystemcontext"
+
+	parseSystemContext(m, path, 1, "Blog | | index | Collides with the generic view")
+
+	assertEqual(t, 1, len(m.Errors), "1 error expected")
+	expectedErr := parseError{File: path, Line: 1, Msg: "SystemContext name is reserved for the generic system context: index"}
+	assertEqual(t, expectedErr, m.Errors[0], "error does not match")
+	assertEqual(t, 0, len(m.SystemContexts), "0 system contexts expected")
+}
+
 func TestParseMultiLineSystem(t *testing.T) {
 	rawValue := " Test System | Test Description spans\\\n multiple \\\nlines | tag1,tag2\nother line"
 	s := bufio.NewScanner(strings.NewReader(rawValue))
@@ -79,6 +93,55 @@ func TestParseCommentLine(t *testing.T) {
 	assertEqual(t, lineCnt, 1, "lineCnt of multiline system does not match")
 }
 
+func TestParseReader(t *testing.T) {
+	src := "System = Test System | Test Description | tag1\nSystem = Test System | Test Description | tag1\n"
+	m, err := ParseReader("test.c4", strings.NewReader(src))
+
+	assertEqual(t, nil, err, "ParseReader returned an error")
+	assertEqual(t, 1, len(m.Systems), "1 system expected")
+	expectedErr := parseError{File: "test.c4", Line: 2, Msg: "System is already defined: Test System"}
+	assertEqual(t, expectedErr, m.Errors[0], "error does not match")
+}
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"project/sys.c4":      {Data: []byte("System = Sys | | \nPersona = User | | ")},
+		"project/rel/rel.c4":  {Data: []byte("Relationship = User | Uses | | Sys |")},
+		"other/unrelated.c4":  {Data: []byte("System = Other | | ")},
+		"project/empty/.keep": {Data: []byte{}},
+	}
+	m, err := ParseFS(fsys, "project")
+
+	assertEqual(t, nil, err, "ParseFS returned an error")
+	assertEqual(t, 0, len(m.Errors), "0 errors expected")
+	assertEqual(t, 1, len(m.Systems), "1 system expected")
+	assertEqual(t, 1, len(m.Relationships), "1 relationship expected")
+}
+
+func TestParseFSLongLines(t *testing.T) {
+	fsys := fstest.MapFS{
+		"project/sys.c4":     {Data: []byte("System = Sys | " + strings.Repeat("x", 70000) + " | ")},
+		"project/binary.bin": {Data: []byte("System = Other | | \n" + strings.Repeat("x", maxLineLength+1))},
+	}
+	m, err := ParseFS(fsys, "project")
+
+	assertEqual(t, nil, err, "ParseFS returned an error")
+	assertEqual(t, 2, len(m.Systems), "2 systems expected")
+	expectedErr := parseError{File: "project/binary.bin", Line: 2, Msg: "line exceeds the maximum length of 16777216 bytes"}
+	assertEqual(t, expectedErr, m.Errors[0], "error does not match")
+}
+
+func TestParseEqualsParseFS(t *testing.T) {
+	m, err := Parse("test/errors")
+	assertEqual(t, nil, err, "Parse returned an error")
+	mFS, err := ParseFS(os.DirFS("test"), "errors")
+	assertEqual(t, nil, err, "ParseFS returned an error")
+
+	assertEqual(t, len(m.Errors), len(mFS.Errors), "error count does not match")
+	assertEqual(t, "test/errors/sys.c4:3: Persona requires 3 elements: Name | Description | Tags", m.Errors[0].Error(), "")
+	assertEqual(t, "errors/sys.c4:3: Persona requires 3 elements: Name | Description | Tags", mFS.Errors[0].Error(), "")
+}
+
 func assertEqual(t *testing.T, a, b interface{}, message string) {
 	if reflect.DeepEqual(a, b) {
 		return
@@ -0,0 +1,394 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+// The queries of this file follow the implied hierarchy of the model: a
+// relationship of a component is also one of its container and its system,
+// so the relationships of an element include those of all elements within
+// it. The elements found are the actual sources and destinations of the
+// relationships, e.g. a component using a database, not its container.
+
+// A Dependency is an element which is reached from another one by following
+// relationships. Depth is the number of relationships in between.
+type Dependency struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind"`
+	Depth int    `json:"depth"`
+}
+
+// An Impact describes what breaks if an element goes down.
+type Impact struct {
+	Element string `json:"element"`
+	// Down contains the element together with all elements within it.
+	Down []string `json:"down"`
+	// Broken contains the relationships from other elements to the
+	// elements which are down.
+	Broken []Relationship `json:"broken"`
+	// Affected contains all elements which depend directly or
+	// transitively on the elements which are down.
+	Affected []Dependency `json:"affected"`
+}
+
+// within returns the set of the element and all elements within it: the
+// containers and components of a system, or the components of a container.
+func (m Model) within(name string) map[string]bool {
+	set := map[string]bool{name: true}
+	for _, c := range m.Containers {
+		if c.System == name {
+			set[c.Name] = true
+		}
+	}
+	for _, c := range m.Components {
+		if set[c.Container] {
+			set[c.Name] = true
+		}
+	}
+	return set
+}
+
+// Outgoing returns the relationships from the element, or elements within
+// it, to other elements.
+func (m Model) Outgoing(name string) ([]Relationship, error) {
+	if _, _, ok := m.Element(name); !ok {
+		return nil, fmt.Errorf("element not found: %s", name)
+	}
+	return m.outgoing(m.within(name)), nil
+}
+
+func (m Model) outgoing(set map[string]bool) []Relationship {
+	rels := make([]Relationship, 0)
+	for _, r := range m.Relationships {
+		if set[r.Source] && !set[r.Destination] {
+			rels = append(rels, r)
+		}
+	}
+	return rels
+}
+
+// Incoming returns the relationships from other elements to the element, or
+// elements within it.
+func (m Model) Incoming(name string) ([]Relationship, error) {
+	if _, _, ok := m.Element(name); !ok {
+		return nil, fmt.Errorf("element not found: %s", name)
+	}
+	return m.incoming(m.within(name)), nil
+}
+
+func (m Model) incoming(set map[string]bool) []Relationship {
+	rels := make([]Relationship, 0)
+	for _, r := range m.Relationships {
+		if !set[r.Source] && set[r.Destination] {
+			rels = append(rels, r)
+		}
+	}
+	return rels
+}
+
+// Dependencies returns the elements the element uses, directly or
+// transitively up to the given depth, ordered by depth and name. A depth of
+// 0 or less means no limit.
+func (m Model) Dependencies(name string, depth int) ([]Dependency, error) {
+	if _, _, ok := m.Element(name); !ok {
+		return nil, fmt.Errorf("element not found: %s", name)
+	}
+	deps, _ := m.traverse(name, depth, false)
+	return deps, nil
+}
+
+// Dependents returns the elements which use the element, directly or
+// transitively up to the given depth, ordered by depth and name. A depth of
+// 0 or less means no limit.
+func (m Model) Dependents(name string, depth int) ([]Dependency, error) {
+	if _, _, ok := m.Element(name); !ok {
+		return nil, fmt.Errorf("element not found: %s", name)
+	}
+	deps, _ := m.traverse(name, depth, true)
+	return deps, nil
+}
+
+// traverse searches the relationships breadth first, starting at the given
+// element. Incoming relationships are followed in reverse if reverse is set.
+// It returns the elements found, and for each of them the relationships by
+// which it was reached from the elements found before.
+func (m Model) traverse(name string, depth int, reverse bool) ([]Dependency, map[string][]Relationship) {
+	seen := m.within(name)
+	via := make(map[string][]Relationship)
+	deps := make([]Dependency, 0)
+
+	current := []string{name}
+	for d := 1; len(current) > 0 && (depth <= 0 || d <= depth); d++ {
+		found := make(map[string]bool)
+		for _, n := range current {
+			rels := m.outgoing(m.within(n))
+			if reverse {
+				rels = m.incoming(m.within(n))
+			}
+			for _, r := range rels {
+				next := r.Destination
+				if reverse {
+					next = r.Source
+				}
+				if seen[next] {
+					continue
+				}
+				found[next] = true
+				via[next] = append(via[next], r)
+			}
+		}
+
+		current = sortedKeys(found)
+		for _, n := range current {
+			seen[n] = true
+			kind, _, _ := m.Element(n)
+			deps = append(deps, Dependency{Name: n, Kind: kind, Depth: d})
+		}
+	}
+	return deps, via
+}
+
+// Paths returns all paths of relationships from one element to another,
+// which do not visit any element, or elements within it, twice and consist
+// of at most maxLen relationships. Since the number of paths grows
+// exponentially with their length, maxLen must be positive.
+func (m Model) Paths(from, to string, maxLen int) ([][]Relationship, error) {
+	for _, name := range []string{from, to} {
+		if _, _, ok := m.Element(name); !ok {
+			return nil, fmt.Errorf("element not found: %s", name)
+		}
+	}
+	if maxLen <= 0 {
+		return nil, fmt.Errorf("maximum length of paths must be positive: %d", maxLen)
+	}
+
+	target := m.within(to)
+	paths := make([][]Relationship, 0)
+	visited := m.within(from)
+	path := make([]Relationship, 0)
+
+	var walk func(name string)
+	walk = func(name string) {
+		if len(path) >= maxLen {
+			return
+		}
+		for _, r := range m.outgoing(m.within(name)) {
+			if visited[r.Destination] {
+				continue
+			}
+			path = append(path, r)
+			if target[r.Destination] {
+				paths = append(paths, append([]Relationship(nil), path...))
+			} else if inner := m.within(r.Destination); !overlaps(inner, visited) {
+				for n := range inner {
+					visited[n] = true
+				}
+				walk(r.Destination)
+				for n := range inner {
+					delete(visited, n)
+				}
+			}
+			path = path[:len(path)-1]
+		}
+	}
+	walk(from)
+
+	sort.SliceStable(paths, func(i, j int) bool { return len(paths[i]) < len(paths[j]) })
+	return paths, nil
+}
+
+// overlaps reports whether two sets of elements have an element in common.
+func overlaps(a, b map[string]bool) bool {
+	for n := range a {
+		if b[n] {
+			return true
+		}
+	}
+	return false
+}
+
+// Impact returns what breaks if the element goes down, together with all
+// elements within it.
+func (m Model) Impact(name string) (Impact, error) {
+	if _, _, ok := m.Element(name); !ok {
+		return Impact{}, fmt.Errorf("element not found: %s", name)
+	}
+	affected, _ := m.traverse(name, 0, true)
+	return Impact{
+		Element:  name,
+		Down:     sortedKeys(m.within(name)),
+		Broken:   m.incoming(m.within(name)),
+		Affected: affected,
+	}, nil
+}
+
+// impactView is centered on a single element and shows all elements which
+// depend on it, directly or transitively, together with the elements it
+// uses directly.
+type impactView struct {
+	Element      string
+	Dependents   []string
+	Dependencies []string
+}
+
+// NewImpactView returns the impact view of an element, which is not part of
+// Views, but can be looked up by its ID like all other views.
+func (m Model) NewImpactView(name string) View {
+	dependents, _ := m.Dependents(name, 0)
+	dependencies, _ := m.Dependencies(name, 1)
+	v := impactView{Element: name, Dependents: make([]string, 0), Dependencies: make([]string, 0)}
+	for _, d := range dependents {
+		v.Dependents = append(v.Dependents, d.Name)
+	}
+	for _, d := range dependencies {
+		v.Dependencies = append(v.Dependencies, d.Name)
+	}
+	return v
+}
+
+func (v impactView) ID() string {
+	return "impact/" + v.Element
+}
+
+func (v impactView) Title() string {
+	return "[Impact] " + v.Element
+}
+
+func (v impactView) Description() string {
+	return "The elements which break if " + v.Element + " goes down, directly or transitively, " +
+		"and the elements it uses."
+}
+
+func (v impactView) Elements() []string {
+	return concat([]string{v.Element}, v.Dependents, v.Dependencies)
+}
+
+func (v impactView) path() string {
+	return impactPath(v.Element)
+}
+
+// impactPath returns the path of the impact view of an element, without
+// building the view.
+func impactPath(name string) string {
+	return "impact/" + url.PathEscape(name) + ".html"
+}
+
+// parent returns the view which contains the element.
+func (v impactView) parent(model Model) View {
+	if c, ok := model.Components[v.Element]; ok {
+		if cont, ok := model.Containers[c.Container]; ok {
+			return model.NewComponentView(cont)
+		}
+	}
+	if c, ok := model.Containers[v.Element]; ok {
+		if sys, ok := model.Systems[c.System]; ok {
+			return model.NewContainerView(sys)
+		}
+	}
+	return model.NewGenericSystemContextView()
+}
+
+func (v impactView) boundary() string {
+	return "The element which goes down"
+}
+
+func (v impactView) graph(model Model) graph {
+	coreNodes := make([]node, 0)
+	topNodes := make([]node, 0)
+	bottomNodes := make([]node, 0)
+	edges := make([]edge, 0)
+
+	if n, ok := elementNode(model, v.Element); ok {
+		coreNodes = append(coreNodes, n)
+	}
+	for _, name := range v.Dependents {
+		if n, ok := elementNode(model, name); ok {
+			topNodes = append(topNodes, n)
+		}
+	}
+	shown := make(map[string]bool)
+	for _, name := range v.Elements() {
+		shown[name] = true
+	}
+	for _, name := range v.Dependencies {
+		if n, ok := elementNode(model, name); ok && !contains(v.Dependents, name) {
+			bottomNodes = append(bottomNodes, n)
+		}
+	}
+	for _, nodes := range [][]node{coreNodes, topNodes, bottomNodes} {
+		for _, n := range nodes {
+			// the nodes lead to the impact views of their elements.
+			n.Attrs["URL"] = "../" + impactPath(n.Name)
+		}
+	}
+
+	// the relationships are connected to the shown elements, which may
+	// contain their actual source or destination.
+	_, via := model.traverse(v.Element, 0, true)
+	for _, name := range v.Dependents {
+		for _, r := range via[name] {
+			e := relationshipEdge(r)
+			e.Destination = shownAncestor(model, r.Destination, shown)
+			if e.Destination != "" {
+				edges = append(edges, e)
+			}
+		}
+	}
+	for _, r := range model.outgoing(model.within(v.Element)) {
+		e := relationshipEdge(r)
+		e.Source = v.Element
+		e.Destination = shownAncestor(model, r.Destination, shown)
+		if e.Destination != "" {
+			edges = append(edges, e)
+		}
+	}
+
+	return graph{Title: v.Title(), Boundary: v.boundary(), CoreNodes: coreNodes, TopNodes: topNodes, BottomNodes: bottomNodes, Edges: edges}
+}
+
+// shownAncestor returns the element itself, or the closest element
+// containing it, which is shown.
+func shownAncestor(model Model, name string, shown map[string]bool) string {
+	for name != "" && !shown[name] {
+		if c, ok := model.Components[name]; ok {
+			name = c.Container
+		} else if c, ok := model.Containers[name]; ok {
+			name = c.System
+		} else {
+			name = ""
+		}
+	}
+	return name
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// elementNode returns the node of any element of the model.
+func elementNode(model Model, name string) (node, bool) {
+	_, elem, ok := model.Element(name)
+	switch e := elem.(type) {
+	case Persona:
+		return personaNode(e), ok
+	case System:
+		return systemNode(e), ok
+	case Container:
+		return containerNode(e), ok
+	case Component:
+		return componentNode(e), ok
+	}
+	return node{}, false
+}
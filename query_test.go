@@ -0,0 +1,156 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDependents(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	deps, err := m.Dependents("Database", 0)
+	assertEqual(t, nil, err, "Dependents returned an error")
+	assertEqual(t, []Dependency{
+		{Name: "Content Server", Kind: "Component", Depth: 1},
+		{Name: "Web App", Kind: "Container", Depth: 1},
+		{Name: "Author", Kind: "Persona", Depth: 2},
+		{Name: "Moderator", Kind: "Persona", Depth: 2},
+		{Name: "Reader", Kind: "Persona", Depth: 2},
+	}, deps, "dependents do not match")
+
+	deps, err = m.Dependents("Database", 1)
+	assertEqual(t, nil, err, "Dependents returned an error")
+	assertEqual(t, 2, len(deps), "depth must be limited")
+
+	_, err = m.Dependents("Unknown", 0)
+	assertEqual(t, "element not found: Unknown", err.Error(), "unknown element must fail")
+}
+
+func TestDependencies(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	deps, err := m.Dependencies("Author", 0)
+	assertEqual(t, nil, err, "Dependencies returned an error")
+	assertEqual(t, []Dependency{
+		{Name: "Web App", Kind: "Container", Depth: 1},
+		{Name: "example.com Blog", Kind: "System", Depth: 1},
+		{Name: "Database", Kind: "Container", Depth: 2},
+		{Name: "Hackernews", Kind: "System", Depth: 2},
+	}, deps, "dependencies do not match")
+
+	// relationships of elements within the system belong to the system.
+	rels, err := m.Outgoing("example.com Blog")
+	assertEqual(t, nil, err, "Outgoing returned an error")
+	assertEqual(t, 1, len(rels), "internal relationships must be ignored")
+	assertEqual(t, "Hackernews", rels[0].Destination, "outgoing relationships do not match")
+}
+
+func TestPaths(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	paths, err := m.Paths("Author", "Database", 10)
+	assertEqual(t, nil, err, "Paths returned an error")
+	assertEqual(t, 2, len(paths), "paths do not match")
+	for _, p := range paths {
+		assertEqual(t, "Author", p[0].Source, "paths must start at the source")
+		assertEqual(t, "Database", p[len(p)-1].Destination, "paths must end at the destination")
+	}
+
+	paths, err = m.Paths("Author", "Database", 1)
+	assertEqual(t, nil, err, "Paths returned an error")
+	assertEqual(t, 0, len(paths), "length of paths must be limited")
+
+	_, err = m.Paths("Author", "Database", 0)
+	assertEqual(t, "maximum length of paths must be positive: 0", err.Error(), "paths without limit must fail")
+}
+
+func TestPathsReenter(t *testing.T) {
+	src := `System = Blog | |
+Container = Blog | Web App | | |
+Container = Blog | Database | | |
+System = Proxy | |
+System = Hackernews | |
+Relationship = Web App | Uses | | Proxy |
+Relationship = Proxy | Uses | | Database |
+Relationship = Database | Uses | | Hackernews |
+`
+	m, err := ParseFS(fstest.MapFS{"blog/blog.c4": {Data: []byte(src)}}, "blog")
+	assertEqual(t, nil, err, "ParseFS returned an error")
+
+	paths, err := m.Paths("Blog", "Hackernews", 10)
+	assertEqual(t, nil, err, "Paths returned an error")
+	assertEqual(t, 1, len(paths), "paths do not match")
+	assertEqual(t, 1, len(paths[0]), "paths must not re-enter the source")
+}
+
+func TestImpact(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	impact, err := m.Impact("Web App")
+	assertEqual(t, nil, err, "Impact returned an error")
+	assertEqual(t, []string{"Content Server", "Web App", "Web Interface Templates"}, impact.Down, "down elements do not match")
+	assertEqual(t, 3, len(impact.Broken), "broken relationships do not match")
+	assertEqual(t, []Dependency{
+		{Name: "Author", Kind: "Persona", Depth: 1},
+		{Name: "Moderator", Kind: "Persona", Depth: 1},
+		{Name: "Reader", Kind: "Persona", Depth: 1},
+	}, impact.Affected, "affected elements do not match")
+}
+
+func TestImpactView(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	view, ok := m.View("impact/Database")
+	assertEqual(t, true, ok, "impact view expected")
+	assertEqual(t, "containers/example.com Blog", view.parent(m).ID(), "parent view does not match")
+
+	g := view.graph(m)
+	assertEqual(t, "Database", g.CoreNodes[0].Name, "core node does not match")
+	assertEqual(t, "../impact/Database.html", g.CoreNodes[0].Attrs["URL"], "nodes must link to impact views")
+	assertEqual(t, 5, len(g.TopNodes), "dependents do not match")
+	edges := make([]string, 0)
+	for _, e := range g.Edges {
+		edges = append(edges, e.Source+" -> "+e.Destination)
+	}
+	assertEqual(t, []string{
+		"Content Server -> Database",
+		"Web App -> Database",
+		"Author -> Web App",
+		"Moderator -> Web App",
+		"Reader -> Web App",
+	}, edges, "edges do not match")
+
+	_, ok = m.View("impact/Unknown")
+	assertEqual(t, false, ok, "impact view of unknown element must not exist")
+}
+
+func TestImpactLinks(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	view, _ := m.View("containers/example.com Blog")
+	p := newViewPage(view, m, m.Views())
+	urls := make([]string, 0)
+	for _, l := range p.Impact {
+		urls = append(urls, l.URL)
+	}
+	assertEqual(t, true, contains(urls, "impact/Database.html"), "page does not link the impact view of its elements")
+
+	buf := new(bytes.Buffer)
+	err = execPage(buf, pageTemplate, p)
+	assertEqual(t, nil, err, "execPage returned an error")
+	assertEqual(t, true, strings.Contains(buf.String(), `<a href="../impact/Database.html">Database</a>`),
+		"page does not contain the impact link")
+}
@@ -0,0 +1,298 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"html/template"
+	"io"
+	"net/url"
+	"strings"
+)
+
+const reportTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<title>{{.Title}}</title>
+	<style>
+	{{- template "layoutStyle"}}
+	{{- template "viewerStyle"}}
+	{{- template "searchStyle"}}
+	.report-section {
+		border-top: 1px solid #dddddd;
+		margin-top: 32px;
+	}
+	.element dt {
+		font-weight: bold;
+	}
+	.element .kind {
+		color: #7b7b7b;
+		font-size: 14px;
+	}
+	</style>
+</head>
+<body>
+	<nav class="sidebar">
+		<a href="#index"><b>Index</b></a>
+		{{template "search" .}}
+		{{template "navTree" .}}
+		<ul>
+			<li><a href="#elements">Elements</a></li>
+		</ul>
+	</nav>
+	<div class="content">
+	<section id="index">
+		<h1>{{.Title}}</h1>
+		<p>{{.Description}}</p>
+
+		{{template "modelErrors" .}}
+
+		{{template "indexList" .}}
+	</section>
+
+	{{- range .Views}}
+
+	<section class="report-section" id="{{.Anchor}}">
+		<div class="breadcrumbs">
+			{{- range .Breadcrumbs}}<a href="{{.URL}}">{{.Title}}</a> &rsaquo; {{end}}{{.Title -}}
+		</div>
+		<h2>{{.Title}}</h2>
+		<p>{{.Description}}</p>
+
+		{{template "graphErrors" .}}
+
+		{{if .Svg}}{{template "viewer" .}}{{end}}
+
+		{{template "legend" .}}
+	</section>
+	{{- end}}
+
+	<section class="report-section" id="elements">
+		<h2>Elements</h2>
+		{{- range .Elements}}
+		<div class="element" id="{{.Anchor}}">
+			<h3>{{.Name}} <span class="kind">[{{.Kind}}]</span></h3>
+			<dl>
+				{{- with .Description}}
+				<dt>Description</dt><dd>{{.}}</dd>
+				{{- end}}
+				{{- with .Technology}}
+				<dt>Technology</dt><dd>{{.}}</dd>
+				{{- end}}
+				{{- with .Parent}}
+				<dt>Part of</dt><dd><a href="{{.URL}}">{{.Title}}</a></dd>
+				{{- end}}
+				{{- if .Tags}}
+				<dt>Tags</dt><dd>{{range $i, $t := .Tags}}{{if $i}}, {{end}}{{$t}}{{end}}</dd>
+				{{- end}}
+				{{- if .Views}}
+				<dt>Views</dt><dd>{{range $i, $v := .Views}}{{if $i}}, {{end}}<a href="{{$v.URL}}">{{$v.Title}}</a>{{end}}</dd>
+				{{- end}}
+				{{- range .APIs}}
+				<dt>API</dt>
+				<dd>{{.Title}}{{with .Version}} {{.}}{{end}} [{{.Specification}}: {{.File}}]
+					{{- if .Operations}}
+					<ul>
+						{{- range .Operations}}
+						<li><code>{{.String}}</code>{{with .Summary}} {{.}}{{end}}</li>
+						{{- end}}
+					</ul>
+					{{- end}}
+				</dd>
+				{{- end}}
+				{{- if .Relationships}}
+				<dt>Relationships</dt>
+				<dd><ul>
+					{{- range .Relationships}}
+					<li>{{template "elementLink" .Source}} {{.Description}} {{template "elementLink" .Destination}}
+						{{- with .Technology}} [{{.}}]{{end}}
+						{{- range $i, $op := .Operations}}{{if $i}},{{else}}:{{end}} <code>{{$op.String}}</code>{{end}}</li>
+					{{- end}}
+				</ul></dd>
+				{{- end}}
+			</dl>
+		</div>
+		{{- end}}
+	</section>
+	</div>
+</body>
+</html>
+
+{{- define "elementLink"}}
+	{{- if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}
+{{- end}}
+`
+
+// A report is the single HTML page containing all views and elements of a
+// model.
+type report struct {
+	page
+	Views    []page
+	Elements []reportElement
+}
+
+// A reportElement describes a single element within the report.
+type reportElement struct {
+	SearchEntry
+	Anchor        string
+	Parent        *navLink
+	APIs          []API
+	Relationships []reportRelationship
+}
+
+// A reportRelationship is a relationship of an element, linking to the
+// elements on both of its ends.
+type reportRelationship struct {
+	Source      navLink
+	Destination navLink
+	Description string
+	Technology  string
+	Operations  []APIOperation
+}
+
+// RenderHTMLReport creates a single, self-contained HTML page which contains
+// all views and elements of the model. All links of the page, including
+// those of the graphs, are anchors within the page, so it can be viewed
+// offline and passed around as a single file.
+func RenderHTMLReport(w io.Writer, model Model) error {
+	views := model.Views()
+	r := report{
+		page: page{
+			Title:         "Architecture Report",
+			Description:   "All views and elements of the current project.",
+			ModelErrors:   model.Errors,
+			ModelWarnings: model.Warnings,
+			Nav:           anchorNav(siteNav(model, views, "")),
+			SearchIndex:   reportSearchIndex(model),
+		},
+		Views:    make([]page, 0),
+		Elements: reportElements(model),
+	}
+
+	links := graphAnchors(views)
+	for _, view := range views {
+		p := page{Title: view.Title(), Description: view.Description(), ModelErrors: model.Errors,
+			ModelWarnings: model.Warnings}
+		p.setBreadcrumbs(view, model)
+		p.setGraph(view, model)
+		p.Svg = template.HTML(links.Replace(string(p.Svg)))
+		p.Breadcrumbs = anchorNav(p.Breadcrumbs)
+		p.Anchor = viewAnchor(view)
+		r.Views = append(r.Views, p)
+	}
+
+	t := template.Must(template.New("layoutTemplate").Parse(layoutTemplate))
+	t = template.Must(t.Parse(viewerTemplate))
+	t = template.Must(t.Parse(searchTemplate))
+	t = template.Must(t.Parse(indexTemplate))
+	t = template.Must(t.New("reportTemplate").Parse(reportTemplate))
+	return t.ExecuteTemplate(w, "reportTemplate", r)
+}
+
+// viewAnchor returns the anchor of a view within the report.
+func viewAnchor(v View) string {
+	return strings.TrimSuffix(v.path(), ".html")
+}
+
+// anchorURL converts the URL of a page, relative to the project root, to the
+// link of the corresponding anchor of the report.
+func anchorURL(pageURL string) string {
+	return "#" + strings.TrimSuffix(pageURL, ".html")
+}
+
+// elementAnchor returns the anchor of an element within the report.
+func elementAnchor(name string) string {
+	return "elements/" + url.PathEscape(name)
+}
+
+// anchorNav converts the URLs of a navigation tree to anchors of the report.
+func anchorNav(links []*navLink) []*navLink {
+	for _, l := range links {
+		l.URL = anchorURL(l.URL)
+		anchorNav(l.Children)
+	}
+	return links
+}
+
+// graphAnchors returns a replacer for the links of the SVG graphs, which
+// point to the pages of other views.
+func graphAnchors(views []View) *strings.Replacer {
+	pairs := make([]string, 0, 2*len(views))
+	for _, v := range views {
+		href := template.HTMLEscapeString("../" + v.path())
+		pairs = append(pairs, `href="`+href+`"`, `href="#`+template.HTMLEscapeString(viewAnchor(v))+`"`)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// reportSearchIndex returns the search index of the model, with all URLs
+// pointing to anchors of the report.
+func reportSearchIndex(model Model) []SearchEntry {
+	entries := model.SearchIndex()
+	for i, e := range entries {
+		if e.Kind == "Relationship" {
+			if e.URL != "" {
+				entries[i].URL = anchorURL(e.URL)
+			}
+		} else {
+			entries[i].URL = "#" + elementAnchor(e.Name)
+		}
+		views := make([]SearchView, len(e.Views))
+		for j, v := range e.Views {
+			views[j] = SearchView{Title: v.Title, URL: anchorURL(v.URL)}
+		}
+		entries[i].Views = views
+	}
+	return entries
+}
+
+func reportElements(model Model) []reportElement {
+	link := func(name string) navLink {
+		if _, _, ok := model.Element(name); !ok {
+			return navLink{Title: name}
+		}
+		return navLink{Title: name, URL: "#" + elementAnchor(name)}
+	}
+
+	elements := make([]reportElement, 0)
+	for _, e := range reportSearchIndex(model) {
+		if e.Kind == "Relationship" {
+			continue
+		}
+		elem := reportElement{SearchEntry: e, Anchor: elementAnchor(e.Name), Relationships: make([]reportRelationship, 0)}
+
+		parent := ""
+		switch e.Kind {
+		case "Container":
+			parent = model.Containers[e.Name].System
+		case "Component":
+			parent = model.Components[e.Name].Container
+		}
+		if parent != "" {
+			l := link(parent)
+			elem.Parent = &l
+		}
+		if e.Kind == "Container" {
+			elem.APIs = model.APISpecs[e.Name].APIs
+		}
+
+		for _, rel := range model.Relationships {
+			if rel.Source != e.Name && rel.Destination != e.Name {
+				continue
+			}
+			elem.Relationships = append(elem.Relationships, reportRelationship{
+				Source:      link(rel.Source),
+				Destination: link(rel.Destination),
+				Description: rel.Description,
+				Technology:  rel.Technology,
+				Operations:  rel.Operations,
+			})
+		}
+		elements = append(elements, elem)
+	}
+	return elements
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderHTMLReport(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	buf := new(bytes.Buffer)
+	err = RenderHTMLReport(buf, m)
+	assertEqual(t, nil, err, "RenderHTMLReport returned an error")
+	html := buf.String()
+
+	for _, v := range m.Views() {
+		assertEqual(t, true, strings.Contains(html, `id="`+viewAnchor(v)+`"`),
+			"report does not contain view "+v.ID())
+	}
+	assertEqual(t, true, strings.Contains(html, `id="elements/Web%20App"`), "report does not contain element")
+	assertEqual(t, true, strings.Contains(html, `href="#components/Web%20App"`), "report does not link view")
+	assertEqual(t, false, strings.Contains(html, `search.js`), "report loads the search index")
+	assertEqual(t, false, strings.Contains(html, `.html"`), "report contains links to pages")
+}
+
+func TestGraphAnchors(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	svg := `<a xlink:href="../containers/example.com%20Blog.html" xlink:title="example.com Blog">`
+	assertEqual(t, `<a xlink:href="#containers/example.com%20Blog" xlink:title="example.com Blog">`,
+		graphAnchors(m.Views()).Replace(svg), "graph link not replaced")
+}
@@ -0,0 +1,374 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// violationColor is the color of elements and relationships violating rules.
+const violationColor = "#d50000"
+
+// violationLegend is the legend entry of highlighted rule violations.
+var violationLegend = legendEntry{Kind: "Violation", Description: "Violates an architecture rule of the project",
+	Color: violationColor, BorderColor: violationColor, Style: "element"}
+
+// ruleFields contains the fields of all rules, as listed in parse errors.
+var ruleFields = map[string][]string{
+	"DenyRelationship":  {"Source Selector", "Destination Selector"},
+	"ContainerBoundary": {"Allowed Selector"},
+	"NoCycles":          {"Kind"},
+	"RequireTag":        {"Selector", "Tag"},
+}
+
+// selectorKinds contains the kinds which can be selected by kind:Kind.
+var selectorKinds = []string{"Persona", "System", "ExternalSystem", "Container", "Component"}
+
+func (m *Model) addRule(path string, lineno int, r Rule) {
+	m.rulePositions = append(m.rulePositions, position{File: path, Line: lineno})
+	m.Rules = append(m.Rules, r)
+}
+
+func parseRule(m *Model, path string, lineno int, kind, value string) {
+	names := ruleFields[kind]
+	fields := strings.Split(value, "|")
+	if len(fields) != len(names) {
+		count := strconv.Itoa(len(names)) + " elements"
+		if len(names) == 1 {
+			count = "1 element"
+		}
+		m.addErr(path, lineno, kind+" requires "+count+": "+strings.Join(names, " | "))
+		return
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+
+	switch kind {
+	case "NoCycles":
+		if fields[0] != "System" && fields[0] != "Container" && fields[0] != "Component" {
+			m.addErr(path, lineno, "invalid kind of NoCycles, must be System, Container or Component: "+fields[0])
+			return
+		}
+	case "RequireTag":
+		if fields[1] == "" {
+			m.addErr(path, lineno, "Tag of RequireTag must not be empty")
+			return
+		}
+	}
+	for i, sel := range fields {
+		if !strings.HasSuffix(names[i], "Selector") {
+			continue
+		}
+		// an empty selector of ContainerBoundary allows no relationship
+		// to cross container boundaries.
+		if sel == "" && kind != "ContainerBoundary" {
+			m.addErr(path, lineno, names[i]+" of "+kind+" must not be empty")
+			return
+		}
+		if k, ok := strings.CutPrefix(sel, "kind:"); ok && !contains(selectorKinds, k) {
+			m.addErr(path, lineno, "invalid kind of selector, must be one of "+strings.Join(selectorKinds, ", ")+": "+k)
+			return
+		}
+	}
+	m.addRule(path, lineno, Rule{Kind: kind, Args: fields})
+}
+
+// String returns the rule in the syntax of project files.
+func (r Rule) String() string {
+	return r.Kind + " = " + strings.Join(r.Args, " | ")
+}
+
+// isElementSelector reports whether a selector of a rule names a single
+// element, instead of selecting elements by tag or kind.
+func isElementSelector(sel string) bool {
+	return sel != "" && sel != "*" && !strings.HasPrefix(sel, "tag:") && !strings.HasPrefix(sel, "kind:")
+}
+
+// matches reports whether the element with the given name is selected by
+// the selector of a rule.
+func (m Model) matches(sel, name string) bool {
+	kind, elem, ok := m.Element(name)
+	if !ok {
+		return false
+	}
+	if tag, ok := strings.CutPrefix(sel, "tag:"); ok {
+		return hasTag(elementTags(elem), tag)
+	}
+	if k, ok := strings.CutPrefix(sel, "kind:"); ok {
+		if k == "ExternalSystem" {
+			return m.isExternal(name)
+		}
+		return k == kind
+	}
+	return sel == "*" || sel == name
+}
+
+// isExternal reports whether the element is a system which is external to
+// all system contexts it is part of.
+func (m Model) isExternal(name string) bool {
+	external := false
+	for _, ctx := range m.SystemContexts {
+		if contains(ctx.CoreSystems, name) {
+			return false
+		}
+		external = external || contains(ctx.ExternalSystems, name)
+	}
+	return external
+}
+
+func elementTags(elem interface{}) []string {
+	switch e := elem.(type) {
+	case Persona:
+		return e.Tags
+	case System:
+		return e.Tags
+	case Container:
+		return e.Tags
+	case Component:
+		return e.Tags
+	}
+	return nil
+}
+
+// hasTag reports whether the tags contain the given one. A trailing * of
+// tag matches any suffix, e.g. owner:* matches owner:team-a.
+func hasTag(tags []string, tag string) bool {
+	prefix, wildcard := strings.CutSuffix(tag, "*")
+	for _, t := range tags {
+		if t == tag || wildcard && strings.HasPrefix(t, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRules checks the model against all of its rules. Violations are
+// reported as errors located at the violating element or relationship, and
+// remembered to highlight them in views.
+func (m *Model) validateRules() {
+	for i, r := range m.Rules {
+		pos := position{}
+		if i < len(m.rulePositions) {
+			pos = m.rulePositions[i]
+		}
+		for j, sel := range r.Args {
+			if !strings.HasSuffix(ruleFields[r.Kind][j], "Selector") || !isElementSelector(sel) {
+				continue
+			}
+			if _, _, ok := m.Element(sel); !ok {
+				m.addErr(pos.File, pos.Line, "Element of "+r.Kind+" is not defined: "+sel)
+			}
+		}
+
+		violated := "violates rule " + strconv.Quote(r.String())
+		switch r.Kind {
+		case "DenyRelationship":
+			for i, rel := range m.Relationships {
+				if m.matches(r.Args[0], rel.Source) && m.matches(r.Args[1], rel.Destination) {
+					m.addViolation(i, "Relationship "+violated+": "+rel.Source+" -> "+rel.Destination)
+				}
+			}
+		case "ContainerBoundary":
+			for i, rel := range m.Relationships {
+				if m.crossesContainer(rel, r.Args[0]) {
+					m.addViolation(i, "Relationship "+violated+": "+rel.Source+" -> "+rel.Destination)
+				}
+			}
+		case "NoCycles":
+			for _, c := range m.cycles(r.Args[0]) {
+				for _, i := range c.relationships[1:] {
+					m.violations[relationshipKey(m.Relationships[i])] = true
+				}
+				m.addViolation(c.relationships[0], "Relationship "+violated+": dependency cycle between "+
+					strings.Join(c.elements, ", "))
+			}
+		case "RequireTag":
+			for _, kind := range []string{"Persona", "System", "Container", "Component"} {
+				for _, name := range m.names(kind) {
+					_, elem, _ := m.Element(name)
+					if !m.matches(r.Args[0], name) || hasTag(elementTags(elem), r.Args[1]) {
+						continue
+					}
+					pos := m.positions[kind+":"+name]
+					m.addErr(pos.File, pos.Line, kind+" "+violated+": "+name)
+					m.violations[name] = true
+				}
+			}
+		}
+	}
+}
+
+func (m *Model) addViolation(i int, msg string) {
+	pos := m.relPosition(i)
+	m.addErr(pos.File, pos.Line, msg)
+	m.violations[relationshipKey(m.Relationships[i])] = true
+}
+
+// names returns the sorted names of all elements of a kind.
+func (m Model) names(kind string) []string {
+	switch kind {
+	case "Persona":
+		return sortedKeys(m.Personas)
+	case "System":
+		return sortedKeys(m.Systems)
+	case "Container":
+		return sortedKeys(m.Containers)
+	case "Component":
+		return sortedKeys(m.Components)
+	}
+	return nil
+}
+
+// crossesContainer reports whether a relationship leads from a component to
+// a component or container of another container, and neither its
+// destination nor the container of the destination is allowed.
+func (m Model) crossesContainer(r Relationship, allowed string) bool {
+	src, ok := m.Components[r.Source]
+	if !ok {
+		return false
+	}
+	dst := r.Destination
+	if c, ok := m.Components[dst]; ok {
+		dst = c.Container
+	} else if _, ok := m.Containers[dst]; !ok {
+		return false
+	}
+	if dst == src.Container {
+		return false
+	}
+	return allowed == "" || !m.matches(allowed, r.Destination) && !m.matches(allowed, dst)
+}
+
+// A cycle is a set of elements depending on each other, together with the
+// indexes of the relationships between them.
+type cycle struct {
+	elements      []string
+	relationships []int
+}
+
+// lift returns the element of the given kind which is or contains the named
+// element, or "" if there is none.
+func (m Model) lift(name, kind string) string {
+	for name != "" {
+		k, _, _ := m.Element(name)
+		if k == kind {
+			return name
+		}
+		switch k {
+		case "Component":
+			name = m.Components[name].Container
+		case "Container":
+			name = m.Containers[name].System
+		default:
+			name = ""
+		}
+	}
+	return ""
+}
+
+// cycles returns the dependency cycles between elements of the given kind,
+// taking the relationships of all elements within them into account. The
+// cycles are the strongly connected components of the dependency graph,
+// found by Tarjan's algorithm.
+func (m Model) cycles(kind string) []cycle {
+	edges := make(map[string]map[string][]int)
+	for i, r := range m.Relationships {
+		src, dst := m.lift(r.Source, kind), m.lift(r.Destination, kind)
+		if src == "" || dst == "" || src == dst {
+			continue
+		}
+		if edges[src] == nil {
+			edges[src] = make(map[string][]int)
+		}
+		edges[src][dst] = append(edges[src][dst], i)
+	}
+
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := make([]string, 0)
+	cycles := make([]cycle, 0)
+
+	var connect func(name string)
+	connect = func(name string) {
+		index[name] = len(index)
+		lowlink[name] = index[name]
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, next := range sortedKeys(edges[name]) {
+			if _, ok := index[next]; !ok {
+				connect(next)
+				lowlink[name] = min(lowlink[name], lowlink[next])
+			} else if onStack[next] {
+				lowlink[name] = min(lowlink[name], index[next])
+			}
+		}
+		if lowlink[name] != index[name] {
+			return
+		}
+
+		members := make(map[string]bool)
+		for {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[n] = false
+			members[n] = true
+			if n == name {
+				break
+			}
+		}
+		if len(members) < 2 {
+			return
+		}
+		c := cycle{elements: sortedKeys(members), relationships: make([]int, 0)}
+		for _, src := range c.elements {
+			for dst, rels := range edges[src] {
+				if members[dst] {
+					c.relationships = append(c.relationships, rels...)
+				}
+			}
+		}
+		sort.Ints(c.relationships)
+		cycles = append(cycles, c)
+	}
+	for _, name := range sortedKeys(edges) {
+		if _, ok := index[name]; !ok {
+			connect(name)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i].relationships[0] < cycles[j].relationships[0] })
+	return cycles
+}
+
+// highlightViolations highlights the nodes and edges of the elements and
+// relationships which violate rules of the model. It reports whether any of
+// them is shown.
+func (g *graph) highlightViolations(model Model) bool {
+	highlighted := false
+	for _, nodes := range [][]node{g.CoreNodes, g.TopNodes, g.BottomNodes} {
+		for _, n := range nodes {
+			if model.violations[n.Name] {
+				n.Attrs["color"] = violationColor
+				n.Attrs["penwidth"] = "3"
+				highlighted = true
+			}
+		}
+	}
+	for _, e := range g.Edges {
+		if e.rel != nil && model.violations[relationshipKey(*e.rel)] {
+			e.Attrs["color"] = violationColor
+			e.Attrs["fontcolor"] = violationColor
+			e.Attrs["penwidth"] = "2"
+			highlighted = true
+		}
+	}
+	return highlighted
+}
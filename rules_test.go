@@ -0,0 +1,106 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"testing"
+)
+
+const rulesSource = `System = Shop | | owner:shop
+System = Payments | |
+System = Mail | |
+SystemContext = Shop | Payments, Mail | Shop Context |
+Container = Shop | Web | | | frontend
+Container = Shop | API | | | api
+Container = Shop | DB | | | database
+Component = Web | Cart | | |
+Component = API | Orders | | |
+Relationship = Web | Reads | SQL | DB |
+Relationship = Cart | Calls | HTTP | Orders |
+Relationship = Cart | Reads | SQL | DB |
+Relationship = Shop | Charges | | Payments |
+Relationship = Payments | Notifies | | Shop |
+Relationship = Payments | Sends | | Mail |
+DenyRelationship = tag:frontend | tag:database
+ContainerBoundary = tag:api
+NoCycles = System
+RequireTag = kind:ExternalSystem | owner:*
+DenyRelationship = Unknown | *
+NoCycles = Persona
+RequireTag = kind:Service | owner
+ContainerBoundary = tag:api | tag:web
+`
+
+func TestParseRules(t *testing.T) {
+	m := parseString(t, rulesSource)
+
+	assertEqual(t, []Rule{
+		{Kind: "DenyRelationship", Args: []string{"tag:frontend", "tag:database"}},
+		{Kind: "ContainerBoundary", Args: []string{"tag:api"}},
+		{Kind: "NoCycles", Args: []string{"System"}},
+		{Kind: "RequireTag", Args: []string{"kind:ExternalSystem", "owner:*"}},
+		{Kind: "DenyRelationship", Args: []string{"Unknown", "*"}},
+	}, m.Rules, "rules do not match")
+
+	errs := make([]string, 0)
+	for _, err := range m.Errors {
+		errs = append(errs, err.Error())
+	}
+	assertEqual(t, []string{
+		"test.c4:21: invalid kind of NoCycles, must be System, Container or Component: Persona",
+		"test.c4:22: invalid kind of selector, must be one of Persona, System, ExternalSystem, Container, Component: Service",
+		"test.c4:23: ContainerBoundary requires 1 element: Allowed Selector",
+		`test.c4:10: Relationship violates rule "DenyRelationship = tag:frontend | tag:database": Web -> DB`,
+		`test.c4:12: Relationship violates rule "ContainerBoundary = tag:api": Cart -> DB`,
+		`test.c4:13: Relationship violates rule "NoCycles = System": dependency cycle between Payments, Shop`,
+		`test.c4:3: System violates rule "RequireTag = kind:ExternalSystem | owner:*": Mail`,
+		`test.c4:2: System violates rule "RequireTag = kind:ExternalSystem | owner:*": Payments`,
+		"test.c4:20: Element of DenyRelationship is not defined: Unknown",
+	}, errs, "errors do not match")
+}
+
+func TestMatchesTagWildcard(t *testing.T) {
+	m := parseString(t, rulesSource)
+
+	assertEqual(t, true, m.matches("tag:owner:*", "Shop"), "wildcard tag selector must match")
+	assertEqual(t, true, m.matches("tag:owner:shop", "Shop"), "tag selector must match")
+	assertEqual(t, false, m.matches("tag:owner", "Shop"), "tag selector must not match a prefix")
+	assertEqual(t, false, m.matches("tag:owner:*", "Payments"), "wildcard tag selector must not match untagged elements")
+	assertEqual(t, true, m.matches("tag:front*", "Web"), "wildcard tag selector must match")
+}
+
+func TestCycles(t *testing.T) {
+	m := parseString(t, rulesSource)
+
+	assertEqual(t, []cycle{{elements: []string{"Payments", "Shop"}, relationships: []int{3, 4}}}, m.cycles("System"),
+		"cycles between systems do not match")
+	assertEqual(t, []cycle{}, m.cycles("Container"), "containers must not contain cycles")
+}
+
+func TestHighlightViolations(t *testing.T) {
+	m := parseString(t, rulesSource)
+
+	view, _ := m.View("containers/Shop")
+	g := viewGraph(view, m)
+	for _, e := range g.Edges {
+		violates := e.Source == "Web" && e.Destination == "DB"
+		assertEqual(t, violates, e.Attrs["color"] == violationColor, e.Source+" -> "+e.Destination+": highlight does not match")
+	}
+	assertEqual(t, violationLegend, g.Legend[len(g.Legend)-1], "legend entry expected")
+
+	view, _ = m.View("contexts/Shop Context")
+	g = viewGraph(view, m)
+	for _, nodes := range [][]node{g.CoreNodes, g.TopNodes, g.BottomNodes} {
+		for _, n := range nodes {
+			violates := n.Name == "Payments" || n.Name == "Mail"
+			assertEqual(t, violates, n.Attrs["color"] == violationColor, n.Name+": highlight does not match")
+		}
+	}
+	for _, e := range g.Edges {
+		violates := e.Destination != "Mail"
+		assertEqual(t, violates, e.Attrs["color"] == violationColor, e.Source+" -> "+e.Destination+": highlight does not match")
+	}
+}
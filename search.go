@@ -0,0 +1,236 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// A SearchEntry is a single searchable element or relationship of a model.
+// URL points to the page which is most relevant for the entry, Views lists
+// all views the entry appears in. All URLs are relative to the project root.
+type SearchEntry struct {
+	Kind        string       `json:"kind"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Technology  string       `json:"technology,omitempty"`
+	Tags        []string     `json:"tags,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Views       []SearchView `json:"views"`
+}
+
+// A SearchView is a reference to a view a SearchEntry appears in.
+type SearchView struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// SearchIndex returns the search index of all elements and relationships of
+// the model.
+func (m Model) SearchIndex() []SearchEntry {
+	// the elements of all views and the views of every element are
+	// collected once, instead of for every entry.
+	views := m.Views()
+	elems := make([]map[string]bool, len(views))
+	byElement := make(map[string][]int)
+	for i, v := range views {
+		elems[i] = make(map[string]bool)
+		for _, e := range v.Elements() {
+			if !elems[i][e] {
+				elems[i][e] = true
+				byElement[e] = append(byElement[e], i)
+			}
+		}
+	}
+	viewsOf := func(name string, others ...string) []SearchView {
+		refs := make([]SearchView, 0)
+		for _, i := range byElement[name] {
+			found := true
+			for _, other := range others {
+				found = found && elems[i][other]
+			}
+			if found {
+				refs = append(refs, SearchView{Title: views[i].Title(), URL: views[i].path()})
+			}
+		}
+		return refs
+	}
+
+	entries := make([]SearchEntry, 0)
+	add := func(e SearchEntry) {
+		if e.URL == "" && len(e.Views) > 0 {
+			e.URL = e.Views[0].URL
+		}
+		e.Tags = nonEmpty(e.Tags)
+		entries = append(entries, e)
+	}
+
+	for _, name := range sortedKeys(m.Personas) {
+		p := m.Personas[name]
+		add(SearchEntry{Kind: "Persona", Name: p.Name, Description: p.Description, Tags: p.Tags,
+			Views: viewsOf(p.Name)})
+	}
+	for _, name := range sortedKeys(m.Systems) {
+		s := m.Systems[name]
+		add(SearchEntry{Kind: "System", Name: s.Name, Description: s.Description, Tags: s.Tags,
+			URL: "containers/" + url.PathEscape(s.Name) + ".html", Views: viewsOf(s.Name)})
+	}
+	for _, name := range sortedKeys(m.Containers) {
+		c := m.Containers[name]
+		add(SearchEntry{Kind: "Container", Name: c.Name, Description: c.Description, Technology: c.Technology, Tags: c.Tags,
+			URL: "components/" + url.PathEscape(c.Name) + ".html", Views: viewsOf(c.Name)})
+	}
+	for _, name := range sortedKeys(m.Components) {
+		c := m.Components[name]
+		add(SearchEntry{Kind: "Component", Name: c.Name, Description: c.Description, Technology: c.Technology, Tags: c.Tags,
+			URL: "components/" + url.PathEscape(c.Container) + ".html", Views: viewsOf(c.Name)})
+	}
+	for _, r := range m.Relationships {
+		add(SearchEntry{Kind: "Relationship", Name: r.Source + " -> " + r.Destination, Description: r.Description,
+			Technology: r.Technology, Tags: r.Tags, Views: viewsOf(r.Source, r.Destination)})
+	}
+	return entries
+}
+
+// RenderSearchIndex writes the search index of the model as JSON.
+func RenderSearchIndex(w io.Writer, model Model) error {
+	return json.NewEncoder(w).Encode(model.SearchIndex())
+}
+
+// RenderSearchScript writes the search index of the model as a JavaScript
+// file, which is loaded by the HTML pages. Unlike plain JSON, a script can
+// also be loaded by pages which are opened directly from the file system.
+func RenderSearchScript(w io.Writer, model Model) error {
+	_, err := io.WriteString(w, "var blueprintSearchIndex = ")
+	if err != nil {
+		return err
+	}
+	err = RenderSearchIndex(w, model)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, ";\n")
+	return err
+}
+
+func nonEmpty(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// searchTemplate embeds the search box into a page. The search index is
+// loaded from search.js in the project root, unless it is inlined into the
+// page, and searched on the client.
+const searchTemplate = `
+{{- define "searchStyle"}}
+	.search input {
+		width: 100%;
+		box-sizing: border-box;
+		margin-top: 8px;
+	}
+	.search ul {
+		padding-left: 0;
+	}
+	.search .kind, .search .views {
+		color: #7b7b7b;
+		font-size: 12px;
+	}
+{{- end}}
+
+{{- define "search"}}
+	<div class="search">
+		<input type="search" id="search-input" placeholder="Search" autocomplete="off">
+		<ul id="search-results"></ul>
+	</div>
+	{{- if .SearchIndex}}
+	<script>var blueprintSearchIndex = {{.SearchIndex}};</script>
+	{{- else}}
+	<script src="{{.Root}}search.js"></script>
+	{{- end}}
+	<script>
+	(function() {
+		var root = {{.Root}};
+		var input = document.getElementById("search-input");
+		var results = document.getElementById("search-results");
+
+		function link(title, url) {
+			var a = document.createElement("a");
+			a.textContent = title;
+			a.href = root + url;
+			return a;
+		}
+
+		function matches(entry, terms) {
+			var text = [entry.kind, entry.name, entry.description, entry.technology, (entry.tags || []).join(" ")]
+				.join(" ").toLowerCase();
+			return terms.every(function(term) {
+				return text.indexOf(term) !== -1;
+			});
+		}
+
+		function search(query) {
+			var terms = query.toLowerCase().split(" ").filter(function(term) {
+				return term !== "";
+			});
+			var found = [];
+			if (terms.length > 0) {
+				(window.blueprintSearchIndex || []).forEach(function(entry) {
+					if (found.length < 50 && matches(entry, terms)) {
+						found.push(entry);
+					}
+				});
+			}
+			return found;
+		}
+
+		function render(found) {
+			results.innerHTML = "";
+			found.forEach(function(entry) {
+				var li = document.createElement("li");
+				li.appendChild(entry.url ? link(entry.name, entry.url) : document.createTextNode(entry.name));
+
+				var kind = document.createElement("span");
+				kind.className = "kind";
+				kind.textContent = " [" + entry.kind + "]";
+				li.appendChild(kind);
+
+				if (entry.views.length > 0) {
+					var views = document.createElement("div");
+					views.className = "views";
+					views.appendChild(document.createTextNode("in: "));
+					entry.views.forEach(function(view, i) {
+						if (i > 0) {
+							views.appendChild(document.createTextNode(", "));
+						}
+						views.appendChild(link(view.title, view.url));
+					});
+					li.appendChild(views);
+				}
+				results.appendChild(li);
+			});
+		}
+
+		input.addEventListener("input", function() {
+			render(search(input.value));
+		});
+		input.addEventListener("keydown", function(e) {
+			var found = search(input.value);
+			if (e.key === "Enter" && found.length > 0 && found[0].url) {
+				window.location.href = root + found[0].url;
+			}
+		});
+	})();
+	</script>
+{{- end}}
+`
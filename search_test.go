@@ -0,0 +1,34 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"testing"
+)
+
+func TestSearchIndex(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	entries := make(map[string]SearchEntry)
+	for _, e := range m.SearchIndex() {
+		entries[e.Kind+":"+e.Name] = e
+	}
+
+	comp := entries["Component:Content Server"]
+	assertEqual(t, "Golang HTTP Server", comp.Technology, "component technology does not match")
+	assertEqual(t, "components/Web%20App.html", comp.URL, "component url does not match")
+	assertEqual(t, []SearchView{{Title: "[Components] Web App", URL: "components/Web%20App.html"}}, comp.Views,
+		"component views do not match")
+
+	pers := entries["Persona:Author"]
+	assertEqual(t, "contexts/index.html", pers.URL, "persona url does not match")
+	assertEqual(t, []string{}, pers.Tags, "empty tags should be omitted")
+
+	rel := entries["Relationship:Web App -> Database"]
+	assertEqual(t, "SQL, port 5432", rel.Technology, "relationship technology does not match")
+	assertEqual(t, "containers/example.com%20Blog.html", rel.URL, "relationship url does not match")
+}
@@ -0,0 +1,367 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bytes"
+	"strings"
+)
+
+// A SyntaxFile is the concrete syntax tree of a single project file. Unlike
+// the Model, it keeps comments, blank lines and the exact location of every
+// keyword and field, so that it can be used to reformat or edit files.
+type SyntaxFile struct {
+	Name  string
+	Stmts []*Stmt
+}
+
+// StmtKind is the kind of a statement of a SyntaxFile.
+type StmtKind int
+
+// The kinds of statements of a SyntaxFile.
+const (
+	BlankStmt   StmtKind = iota // an empty line
+	CommentStmt                 // a line beginning with #
+	ElementStmt                 // a line of the form Keyword = Field | Field ...
+	InvalidStmt                 // a line without =
+)
+
+// A Stmt is a single, possibly continued, line of a SyntaxFile.
+type Stmt struct {
+	Kind StmtKind
+	// Lines are the raw source lines of the statement, which are more than
+	// one if lines are continued with \.
+	Lines   []string
+	Start   Pos
+	Keyword Token
+	Fields  []Token
+
+	// text is the joined text of all lines, pos contains the position of
+	// each of its bytes, eq is the index of the = separating the keyword.
+	text string
+	pos  []Pos
+	eq   int
+}
+
+// canonicalKeywords maps all accepted keywords to their canonical form.
+var canonicalKeywords = map[string]string{
+	"Persona":        "Persona",
+	"Person":         "Persona",
+	"System":         "System",
+	"SoftwareSystem": "System",
+	"Container":      "Container",
+	"Component":      "Component",
+	"Relationship":   "Relationship",
+	"SystemContext":  "SystemContext",
+	"ViewOptions":    "ViewOptions",
+	"SameRank":       "SameRank",
+	"Above":          "Above",
+	"Pin":            "Pin",
+	"EdgeOptions":    "EdgeOptions",
+
+	"DenyRelationship":  "DenyRelationship",
+	"ContainerBoundary": "ContainerBoundary",
+	"NoCycles":          "NoCycles",
+	"RequireTag":        "RequireTag",
+	"GoPackage":         "GoPackage",
+	"APISpec":           "APISpec",
+}
+
+// CanonicalKeyword returns the canonical form of a keyword, e.g. Persona for
+// Person. The result is false for unknown keywords.
+func CanonicalKeyword(keyword string) (string, bool) {
+	k, ok := canonicalKeywords[keyword]
+	return k, ok
+}
+
+// Keywords returns all canonical keywords.
+func Keywords() []string {
+	return []string{
+		"Persona", "System", "Container", "Component", "Relationship", "SystemContext",
+		"ViewOptions", "SameRank", "Above", "Pin", "EdgeOptions",
+		"DenyRelationship", "ContainerBoundary", "NoCycles", "RequireTag", "GoPackage",
+		"APISpec",
+	}
+}
+
+// A Pos is a position within a file. Line and Col start at 1, Col counts
+// bytes.
+type Pos struct {
+	Line int
+	Col  int
+}
+
+// A Token is a keyword or field of an element. Text is trimmed and continued
+// lines are joined in the same way as the parser does. End is the position
+// after the last character of the token.
+type Token struct {
+	Text  string
+	Start Pos
+	End   Pos
+
+	// from and to are the offsets of the token in the joined text of its
+	// statement.
+	from, to int
+}
+
+// Contains reports whether the position p is within the token.
+func (t Token) Contains(p Pos) bool {
+	return !posLess(p, t.Start) && !posLess(t.End, p)
+}
+
+func posLess(a, b Pos) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+}
+
+// ParseSyntax parses the source of a single file into its concrete syntax
+// tree. Parsing is lossless and never fails: statements which can not be
+// recognized are kept as InvalidStmt, and lines are not limited in length.
+func ParseSyntax(name string, src []byte) *SyntaxFile {
+	f := &SyntaxFile{Name: name, Stmts: make([]*Stmt, 0)}
+
+	s := newLineScanner(src)
+	lineno := 0
+	for s.Scan() {
+		lineno++
+		stmt := &Stmt{Lines: []string{s.Text()}, Start: Pos{Line: lineno, Col: 1}}
+		f.Stmts = append(f.Stmts, stmt)
+
+		text := strings.TrimSpace(s.Text())
+		switch {
+		case text == "":
+			stmt.Kind = BlankStmt
+			continue
+		case strings.HasPrefix(text, "#"):
+			stmt.Kind = CommentStmt
+			continue
+		}
+
+		// continued lines are joined like parseLine does.
+		for strings.HasSuffix(strings.TrimSpace(stmt.Lines[len(stmt.Lines)-1]), "\\") && s.Scan() {
+			lineno++
+			stmt.Lines = append(stmt.Lines, s.Text())
+		}
+		stmt.parseFields()
+	}
+	return f
+}
+
+// A lineScanner splits source into lines like bufio.ScanLines does, but
+// without a limit of the line length.
+type lineScanner struct {
+	src  []byte
+	line string
+}
+
+func newLineScanner(src []byte) *lineScanner {
+	return &lineScanner{src: src}
+}
+
+// Scan advances to the next line and reports whether there is one.
+func (s *lineScanner) Scan() bool {
+	if len(s.src) == 0 {
+		return false
+	}
+	line := s.src
+	if i := bytes.IndexByte(s.src, '\n'); i >= 0 {
+		line, s.src = s.src[:i], s.src[i+1:]
+	} else {
+		s.src = nil
+	}
+	s.line = string(bytes.TrimSuffix(line, []byte("\r")))
+	return true
+}
+
+// Text returns the current line without its line ending.
+func (s *lineScanner) Text() string {
+	return s.line
+}
+
+// parseFields splits the joined lines of an element into its tokens and
+// records their positions.
+func (stmt *Stmt) parseFields() {
+	text := make([]byte, 0)
+	positions := make([]Pos, 0)
+	for i, line := range stmt.Lines {
+		start := len(line) - len(strings.TrimLeft(line, " \t"))
+		content := strings.TrimSpace(line)
+		if i < len(stmt.Lines)-1 {
+			content = strings.TrimSpace(strings.TrimSuffix(content, "\\"))
+		}
+		if i > 0 {
+			// the space joining two lines belongs to the end of the
+			// previous line.
+			prev := Pos{Line: stmt.Start.Line + i - 1, Col: 0}
+			if len(positions) > 0 {
+				prev = positions[len(positions)-1]
+			}
+			text = append(text, ' ')
+			positions = append(positions, Pos{Line: prev.Line, Col: prev.Col + 1})
+		}
+		for j := 0; j < len(content); j++ {
+			text = append(text, content[j])
+			positions = append(positions, Pos{Line: stmt.Start.Line + i, Col: start + j + 1})
+		}
+	}
+
+	stmt.text = string(text)
+	stmt.pos = positions
+
+	stmt.eq = bytes.IndexByte(text, '=')
+	if stmt.eq == -1 {
+		stmt.Kind = InvalidStmt
+		return
+	}
+	stmt.Kind = ElementStmt
+	stmt.Keyword = stmt.token(0, stmt.eq)
+	stmt.Fields = stmt.split(stmt.eq+1, len(text), '|')
+}
+
+// token returns the trimmed token between the offsets from and to of the
+// joined text.
+func (stmt *Stmt) token(from, to int) Token {
+	for from < to && isSpace(stmt.text[from]) {
+		from++
+	}
+	for to > from && isSpace(stmt.text[to-1]) {
+		to--
+	}
+	if from == to {
+		var p Pos
+		if from < len(stmt.pos) {
+			p = stmt.pos[from]
+		} else if len(stmt.pos) > 0 {
+			p = stmt.pos[len(stmt.pos)-1]
+			p.Col++
+		}
+		return Token{Start: p, End: p, from: from, to: to}
+	}
+	end := stmt.pos[to-1]
+	end.Col++
+	return Token{Text: stmt.text[from:to], Start: stmt.pos[from], End: end, from: from, to: to}
+}
+
+// split splits the joined text between the offsets from and to into tokens.
+func (stmt *Stmt) split(from, to int, sep byte) []Token {
+	tokens := make([]Token, 0)
+	start := from
+	for i := from; i <= to; i++ {
+		if i == to || stmt.text[i] == sep {
+			tokens = append(tokens, stmt.token(start, i))
+			start = i + 1
+		}
+	}
+	return tokens
+}
+
+// SplitList splits a field holding a comma separated list into its items.
+func (stmt *Stmt) SplitList(field Token) []Token {
+	return stmt.split(field.from, field.to, ',')
+}
+
+// FieldAt returns the index of the field at the position p, or -1 if p is
+// not located within a field.
+func (stmt *Stmt) FieldAt(p Pos) int {
+	if stmt.Kind != ElementStmt {
+		return -1
+	}
+	offset := 0
+	for offset < len(stmt.pos) && posLess(stmt.pos[offset], p) {
+		offset++
+	}
+	if offset <= stmt.eq {
+		return -1
+	}
+	return strings.Count(stmt.text[stmt.eq+1:offset], "|")
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t'
+}
+
+// Value returns the value of the element as parseLine would return it.
+func (stmt *Stmt) Value() string {
+	fields := make([]string, len(stmt.Fields))
+	for i, f := range stmt.Fields {
+		fields[i] = f.Text
+	}
+	return strings.Join(fields, " | ")
+}
+
+// End returns the position after the last character of the statement.
+func (stmt *Stmt) End() Pos {
+	last := stmt.Lines[len(stmt.Lines)-1]
+	return Pos{Line: stmt.Start.Line + len(stmt.Lines) - 1, Col: len(last) + 1}
+}
+
+// A NameRef is a token which names an element of the model, either by
+// defining it or by referencing it.
+type NameRef struct {
+	Token
+	// Kind is the kind of the named element, e.g. System, or empty if
+	// elements of any kind can be referenced.
+	Kind string
+	Def  bool
+}
+
+// Names returns all tokens of the statement which name elements.
+func (stmt *Stmt) Names() []NameRef {
+	keyword, ok := canonicalKeywords[stmt.Keyword.Text]
+	if stmt.Kind != ElementStmt || !ok {
+		return nil
+	}
+
+	names := make([]NameRef, 0)
+	add := func(field int, kind string, def bool) {
+		if field >= len(stmt.Fields) {
+			return
+		}
+		tokens := []Token{stmt.Fields[field]}
+		if (keyword == "SystemContext" || keyword == "SameRank") && !def {
+			tokens = stmt.SplitList(stmt.Fields[field])
+		}
+		for _, t := range tokens {
+			if t.Text != "" {
+				names = append(names, NameRef{Token: t, Kind: kind, Def: def})
+			}
+		}
+	}
+
+	switch keyword {
+	case "Persona", "System":
+		add(0, keyword, true)
+	case "Container":
+		add(0, "System", false)
+		add(1, keyword, true)
+	case "Component":
+		add(0, "Container", false)
+		add(1, keyword, true)
+	case "GoPackage":
+		add(0, "Component", false)
+	case "APISpec":
+		add(0, "Container", false)
+	case "Relationship":
+		add(0, "", false)
+		add(3, "", false)
+	case "SystemContext":
+		add(0, "System", false)
+		add(1, "System", false)
+		add(2, keyword, true)
+	case "SameRank", "Pin":
+		add(1, "", false)
+	case "Above", "EdgeOptions":
+		add(1, "", false)
+		add(2, "", false)
+	case "DenyRelationship", "ContainerBoundary", "RequireTag":
+		// only selectors naming single elements are references.
+		for i, f := range stmt.Fields {
+			if i < len(ruleFields[keyword]) && strings.HasSuffix(ruleFields[keyword][i], "Selector") && isElementSelector(f.Text) {
+				add(i, "", false)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseSyntax(t *testing.T) {
+	src := "# comment\n\nSystem = Test System | Test \\\n\tDescription | tag1\nfoo\n"
+	f := ParseSyntax("test.c4", []byte(src))
+
+	assertEqual(t, 4, len(f.Stmts), "4 statements expected")
+	assertEqual(t, CommentStmt, f.Stmts[0].Kind, "comment expected")
+	assertEqual(t, BlankStmt, f.Stmts[1].Kind, "blank line expected")
+	assertEqual(t, InvalidStmt, f.Stmts[3].Kind, "invalid statement expected")
+
+	sys := f.Stmts[2]
+	assertEqual(t, ElementStmt, sys.Kind, "element expected")
+	assertEqual(t, 2, len(sys.Lines), "2 lines expected")
+	assertEqual(t, Token{Text: "System", Start: Pos{3, 1}, End: Pos{3, 7}}, exported(sys.Keyword), "keyword does not match")
+	expectedFields := []Token{
+		{Text: "Test System", Start: Pos{3, 10}, End: Pos{3, 21}},
+		{Text: "Test Description", Start: Pos{3, 24}, End: Pos{4, 13}},
+		{Text: "tag1", Start: Pos{4, 16}, End: Pos{4, 20}},
+	}
+	for i, field := range sys.Fields {
+		assertEqual(t, expectedFields[i], exported(field), "field does not match")
+	}
+	assertEqual(t, true, sys.Fields[1].Contains(Pos{4, 2}), "description should contain position")
+	assertEqual(t, false, sys.Fields[1].Contains(Pos{4, 15}), "description should not contain position")
+}
+
+func TestParseSyntaxMatchesParseLine(t *testing.T) {
+	src := " Container = Sys | Name | spans\\\n multiple \\\n\tlines | tech | \\\n"
+	f := ParseSyntax("test.c4", []byte(src))
+
+	s := bufio.NewScanner(strings.NewReader(src))
+	s.Scan()
+	line, lineCnt := parseLine(s)
+	i := strings.Index(line, "=")
+
+	assertEqual(t, 1, len(f.Stmts), "1 statement expected")
+	assertEqual(t, lineCnt, len(f.Stmts[0].Lines), "line count does not match")
+	assertEqual(t, strings.TrimSpace(line[:i]), f.Stmts[0].Keyword.Text, "keyword does not match")
+	assertEqual(t, strings.TrimSpace(line[i+1:]), f.Stmts[0].Value(), "value does not match")
+}
+
+func TestSplitList(t *testing.T) {
+	f := ParseSyntax("test.c4", []byte("SystemContext = A, B ,C | | Name | Description"))
+	stmt := f.Stmts[0]
+
+	expectedItems := []Token{
+		{Text: "A", Start: Pos{1, 17}, End: Pos{1, 18}},
+		{Text: "B", Start: Pos{1, 20}, End: Pos{1, 21}},
+		{Text: "C", Start: Pos{1, 23}, End: Pos{1, 24}},
+	}
+	items := stmt.SplitList(stmt.Fields[0])
+	assertEqual(t, 3, len(items), "3 items expected")
+	for i, item := range items {
+		assertEqual(t, expectedItems[i], exported(item), "item does not match")
+	}
+
+	assertEqual(t, -1, stmt.FieldAt(Pos{1, 5}), "keyword should not be a field")
+	assertEqual(t, 0, stmt.FieldAt(Pos{1, 17}), "field 0 expected")
+	assertEqual(t, 1, stmt.FieldAt(Pos{1, 27}), "field 1 expected")
+	assertEqual(t, 3, stmt.FieldAt(Pos{1, 40}), "field 3 expected")
+}
+
+// exported strips the unexported fields of a token.
+func exported(t Token) Token {
+	return Token{Text: t.Text, Start: t.Start, End: t.End}
+}
+
+func TestNames(t *testing.T) {
+	f := ParseSyntax("test.c4", []byte("Container = Sys | Cont | | |\nSystemContext = A, B | C | Ctx | \nRelationship = X | Uses | | Y |"))
+
+	names := make([]string, 0)
+	for _, stmt := range f.Stmts {
+		for _, n := range stmt.Names() {
+			names = append(names, fmt.Sprintf("%s:%s:%v", n.Kind, n.Text, n.Def))
+		}
+	}
+	expectedNames := []string{
+		"System:Sys:false", "Container:Cont:true",
+		"System:A:false", "System:B:false", "System:C:false", "SystemContext:Ctx:true",
+		":X:false", ":Y:false",
+	}
+	assertEqual(t, expectedNames, names, "names do not match")
+}
+
+func TestParseSyntaxFiles(t *testing.T) {
+	sys := ParseSyntax("sys.c4", []byte("System = Sys | A system. |\nPersona = User | | "))
+	rel := ParseSyntax("rel.c4", []byte("\nRelationship = User | Uses | | Unknown |"))
+
+	m := ParseSyntaxFiles(sys, rel)
+	assertEqual(t, "A system.", m.Systems["Sys"].Description, "system description does not match")
+	expectedErrs := []error{
+		parseError{File: "rel.c4", Line: 2, Msg: "Destination of Relationship is not defined: Unknown"},
+	}
+	assertEqual(t, expectedErrs, m.Errors, "errors do not match")
+	expectedWarnings := []error{
+		parseError{File: "sys.c4", Line: 1, Msg: "System is not used in any Relationship: Sys", Warning: true},
+	}
+	assertEqual(t, expectedWarnings, m.Warnings, "warnings do not match")
+}
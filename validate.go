@@ -0,0 +1,89 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+// validate checks the references between the elements of a completely
+// parsed model. Undefined references are reported as errors, suspicious but
+// valid definitions as warnings.
+func (m *Model) validate() {
+	for _, name := range sortedKeys(m.Containers) {
+		c := m.Containers[name]
+		if _, ok := m.Systems[c.System]; !ok {
+			pos := m.positions["Container:"+name]
+			m.addErr(pos.File, pos.Line, "System of Container is not defined: "+c.System)
+		}
+	}
+
+	for _, name := range sortedKeys(m.Components) {
+		c := m.Components[name]
+		if _, ok := m.Containers[c.Container]; !ok {
+			pos := m.positions["Component:"+name]
+			m.addErr(pos.File, pos.Line, "Container of Component is not defined: "+c.Container)
+		}
+	}
+
+	for _, name := range sortedKeys(m.SystemContexts) {
+		ctx := m.SystemContexts[name]
+		pos := m.positions["SystemContext:"+name]
+		for _, sys := range concat(ctx.CoreSystems, ctx.ExternalSystems) {
+			if _, ok := m.Systems[sys]; !ok && sys != "" {
+				m.addErr(pos.File, pos.Line, "System of SystemContext is not defined: "+sys)
+			}
+		}
+	}
+
+	for _, view := range sortedKeys(m.ViewOptions) {
+		if _, ok := m.View(view); !ok && view != "" {
+			pos := m.positions["ViewOptions:"+view]
+			m.addErr(pos.File, pos.Line, "View of ViewOptions is not defined: "+view)
+		}
+	}
+
+	m.validateHints()
+	m.validateRules()
+	m.validateGoPackages()
+	m.validateAPISpecs()
+
+	used := make(map[string]bool)
+	seen := make(map[string]bool)
+	for i, r := range m.Relationships {
+		pos := m.relPosition(i)
+		if _, _, ok := m.Element(r.Source); !ok {
+			m.addErr(pos.File, pos.Line, "Source of Relationship is not defined: "+r.Source)
+		}
+		if _, _, ok := m.Element(r.Destination); !ok {
+			m.addErr(pos.File, pos.Line, "Destination of Relationship is not defined: "+r.Destination)
+		}
+
+		key := r.Source + "\x00" + r.Description + "\x00" + r.Destination
+		if seen[key] {
+			m.addWarn(pos.File, pos.Line, "Relationship is already defined: "+r.Source+" -> "+r.Destination)
+		}
+		seen[key] = true
+		used[r.Source] = true
+		used[r.Destination] = true
+	}
+
+	for _, name := range sortedKeys(m.Personas) {
+		if !used[name] {
+			pos := m.positions["Persona:"+name]
+			m.addWarn(pos.File, pos.Line, "Persona is not used in any Relationship: "+name)
+		}
+	}
+	for _, name := range sortedKeys(m.Systems) {
+		if !used[name] {
+			pos := m.positions["System:"+name]
+			m.addWarn(pos.File, pos.Line, "System is not used in any Relationship: "+name)
+		}
+	}
+}
+
+func (m *Model) relPosition(i int) position {
+	if i < len(m.relPositions) {
+		return m.relPositions[i]
+	}
+	return position{}
+}
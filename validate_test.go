@@ -0,0 +1,50 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"testing"
+)
+
+func TestValidateOk(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+	assertEqual(t, 0, len(m.Errors), "0 errors expected")
+}
+
+func TestValidateErrors(t *testing.T) {
+	m, err := Parse("test/errors")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	expectedErr := parseError{File: "test/errors/sys.c4", Line: 15, Msg: "Destination of Relationship is not defined: Non Existant Destination"}
+	assertEqual(t, expectedErr, m.Errors[len(m.Errors)-1], "error does not match")
+}
+
+func TestValidateReferences(t *testing.T) {
+	m := newModel()
+	path := "test/validate"
+	parseSystem(m, path, 1, "Sys | | ")
+	parseContainer(m, path, 2, "Unknown Sys | Cont | | | ")
+	parseComponent(m, path, 3, "Unknown Cont | Comp | | | ")
+	parseRelationship(m, path, 4, "Sys | Uses | | Comp | ")
+	parseRelationship(m, path, 5, "Sys | Uses | | Comp | ")
+	parsePersona(m, path, 6, "Pers | | ")
+	m.validate()
+
+	expectedErrs := []error{
+		parseError{File: path, Line: 2, Msg: "System of Container is not defined: Unknown Sys"},
+		parseError{File: path, Line: 3, Msg: "Container of Component is not defined: Unknown Cont"},
+	}
+	assertEqual(t, expectedErrs, m.Errors, "errors do not match")
+	expectedWarnings := []error{
+		parseError{File: path, Line: 5, Msg: "Relationship is already defined: Sys -> Comp", Warning: true},
+		parseError{File: path, Line: 6, Msg: "Persona is not used in any Relationship: Pers", Warning: true},
+	}
+	assertEqual(t, expectedWarnings, m.Warnings, "warnings do not match")
+	assertEqual(t, true, IsWarning(m.Warnings[1]), "warning expected")
+	assertEqual(t, "test/validate:6: warning: Persona is not used in any Relationship: Pers", m.Warnings[1].Error(),
+		"warning message does not match")
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+// viewerTemplate embeds the diagram viewer into a page. The script adds
+// mouse-wheel zoom, drag panning and fit-to-screen to the inlined SVG graph
+// and highlights the hovered element together with its direct relationships.
+// It is completely self-contained, so it also works for exported pages which
+// are viewed offline.
+const viewerTemplate = `
+{{- define "viewerStyle"}}
+	.diagram {
+		position: relative;
+		height: 80vh;
+		border: 1px solid #dddddd;
+		overflow: hidden;
+		cursor: grab;
+	}
+	.diagram.dragging {
+		cursor: grabbing;
+	}
+	.diagram > svg {
+		width: 100%;
+		height: 100%;
+	}
+	.diagram-toolbar {
+		position: absolute;
+		top: 8px;
+		right: 8px;
+	}
+	.diagram-toolbar button {
+		min-width: 32px;
+	}
+	svg.highlighting g.node,
+	svg.highlighting g.edge {
+		opacity: 0.15;
+	}
+	svg.highlighting g.node.highlight,
+	svg.highlighting g.edge.highlight {
+		opacity: 1;
+	}
+{{- end}}
+
+{{- define "viewer"}}
+	<div class="diagram" id="{{.DiagramID}}">
+		<div class="diagram-toolbar">
+			<button type="button" data-zoom="in" title="Zoom in">+</button>
+			<button type="button" data-zoom="out" title="Zoom out">&minus;</button>
+			<button type="button" data-zoom="fit" title="Fit to screen">Fit</button>
+		</div>
+		{{.Svg}}
+	</div>
+	<script>
+	(function() {
+		var container = document.getElementById({{.DiagramID}});
+		var svg = container && container.querySelector("svg");
+		if (!svg || !svg.viewBox || !svg.viewBox.baseVal) {
+			return;
+		}
+
+		var vb = svg.viewBox.baseVal;
+		var original = {x: vb.x, y: vb.y, width: vb.width, height: vb.height};
+		svg.removeAttribute("width");
+		svg.removeAttribute("height");
+		svg.setAttribute("preserveAspectRatio", "xMidYMid meet");
+
+		function setViewBox(x, y, width, height) {
+			svg.setAttribute("viewBox", [x, y, width, height].join(" "));
+		}
+
+		function fit() {
+			setViewBox(original.x, original.y, original.width, original.height);
+		}
+
+		// toSvg converts client coordinates to the coordinates of the viewBox.
+		function toSvg(clientX, clientY) {
+			var pt = svg.createSVGPoint();
+			pt.x = clientX;
+			pt.y = clientY;
+			return pt.matrixTransform(svg.getScreenCTM().inverse());
+		}
+
+		// zoom scales the viewBox by factor, keeping the point (x, y) fixed.
+		function zoom(factor, x, y) {
+			var v = svg.viewBox.baseVal;
+			setViewBox(x - (x - v.x) * factor, y - (y - v.y) * factor, v.width * factor, v.height * factor);
+		}
+
+		function zoomCenter(factor) {
+			var v = svg.viewBox.baseVal;
+			zoom(factor, v.x + v.width / 2, v.y + v.height / 2);
+		}
+
+		container.addEventListener("wheel", function(e) {
+			e.preventDefault();
+			var p = toSvg(e.clientX, e.clientY);
+			zoom(e.deltaY < 0 ? 0.9 : 1 / 0.9, p.x, p.y);
+		}, {passive: false});
+
+		var dragStart = null;
+		var dragged = false;
+		container.addEventListener("mousedown", function(e) {
+			if (e.button !== 0 || e.target.closest(".diagram-toolbar")) {
+				return;
+			}
+			dragStart = toSvg(e.clientX, e.clientY);
+			dragged = false;
+			container.classList.add("dragging");
+			e.preventDefault();
+		});
+		window.addEventListener("mousemove", function(e) {
+			if (!dragStart) {
+				return;
+			}
+			var p = toSvg(e.clientX, e.clientY);
+			var v = svg.viewBox.baseVal;
+			if (Math.abs(p.x - dragStart.x) + Math.abs(p.y - dragStart.y) > 1) {
+				dragged = true;
+			}
+			setViewBox(v.x - (p.x - dragStart.x), v.y - (p.y - dragStart.y), v.width, v.height);
+		});
+		window.addEventListener("mouseup", function() {
+			dragStart = null;
+			container.classList.remove("dragging");
+		});
+		// links of the graph must not be followed at the end of a drag.
+		container.addEventListener("click", function(e) {
+			if (dragged) {
+				e.preventDefault();
+				e.stopPropagation();
+				dragged = false;
+			}
+		}, true);
+
+		container.querySelectorAll(".diagram-toolbar button").forEach(function(b) {
+			b.addEventListener("click", function() {
+				switch (b.getAttribute("data-zoom")) {
+				case "in":
+					zoomCenter(0.8);
+					break;
+				case "out":
+					zoomCenter(1 / 0.8);
+					break;
+				default:
+					fit();
+				}
+			});
+		});
+
+		// graphviz stores the node names and "source->destination" of edges
+		// in the title elements of the generated SVG groups.
+		var nodes = {};
+		svg.querySelectorAll("g.node").forEach(function(g) {
+			var title = g.querySelector("title");
+			if (title) {
+				nodes[title.textContent] = {elem: g, edges: []};
+			}
+		});
+		// invisible edges of layout hints are not relationships.
+		svg.querySelectorAll("g.edge:not(.layout-hint)").forEach(function(g) {
+			var title = g.querySelector("title");
+			if (!title) {
+				return;
+			}
+			var text = title.textContent;
+			for (var i = text.indexOf("->"); i !== -1; i = text.indexOf("->", i + 1)) {
+				var src = nodes[text.substring(0, i)];
+				var dst = nodes[text.substring(i + 2)];
+				if (src && dst) {
+					var edge = {elem: g, ends: [src, dst]};
+					src.edges.push(edge);
+					dst.edges.push(edge);
+					g.addEventListener("mouseenter", function() {
+						highlight([edge.elem, src.elem, dst.elem]);
+					});
+					g.addEventListener("mouseleave", clear);
+					break;
+				}
+			}
+		});
+
+		function highlight(elems) {
+			svg.classList.add("highlighting");
+			elems.forEach(function(elem) {
+				elem.classList.add("highlight");
+			});
+		}
+
+		function clear() {
+			svg.classList.remove("highlighting");
+			svg.querySelectorAll(".highlight").forEach(function(elem) {
+				elem.classList.remove("highlight");
+			});
+		}
+
+		Object.keys(nodes).forEach(function(name) {
+			var n = nodes[name];
+			n.elem.addEventListener("mouseenter", function() {
+				var elems = [n.elem];
+				n.edges.forEach(function(edge) {
+					elems.push(edge.elem, edge.ends[0].elem, edge.ends[1].elem);
+				});
+				highlight(elems);
+			});
+			n.elem.addEventListener("mouseleave", clear);
+		});
+	})();
+	</script>
+{{- end}}
+`
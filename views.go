@@ -6,20 +6,39 @@
 package blueprint
 
 import (
-	"io"
+	"net/url"
+	"sort"
+	"strings"
 )
 
 // A View represents a specific subset of entities of a complete model.
 // It can be rendered using RenderHTMLPage.
 type View interface {
+	// ID identifies the view within its model, e.g. "components/Web App".
+	ID() string
 	Title() string
 	Description() string
-	dot(w io.Writer, model Model) error
+	// Elements returns the names of all elements shown in the view.
+	Elements() []string
+	// graph returns the graph of the view, without any view options
+	// applied.
+	graph(model Model) graph
+	// path returns the location of the rendered view relative to the
+	// root of the project.
+	path() string
+	// parent returns the view one level up the C4 hierarchy, or nil if
+	// the view is the topmost one.
+	parent(model Model) View
 }
 
+// genericContext is the name of the generic system context view within
+// the contexts, which can not be used by a SystemContext.
+const genericContext = "index"
+
 type systemContextView struct {
 	title           string
 	description     string
+	generic         bool
 	CoreSystems     []string
 	ExternalSystems []string
 	Personas        []string
@@ -33,6 +52,31 @@ func (v systemContextView) Title() string {
 	return "[System Context] " + v.title
 }
 
+func (v systemContextView) ID() string {
+	if v.generic {
+		return "contexts/" + genericContext
+	}
+	return "contexts/" + v.title
+}
+
+func (v systemContextView) path() string {
+	if v.generic {
+		return "contexts/" + genericContext + ".html"
+	}
+	return "contexts/" + url.PathEscape(v.title) + ".html"
+}
+
+func (v systemContextView) parent(model Model) View {
+	if v.generic {
+		return nil
+	}
+	return model.NewGenericSystemContextView()
+}
+
+func (v systemContextView) Elements() []string {
+	return concat(v.CoreSystems, v.ExternalSystems, v.Personas)
+}
+
 type containerView struct {
 	title       string
 	description string
@@ -50,6 +94,22 @@ func (v containerView) Title() string {
 	return "[Containers] " + v.title
 }
 
+func (v containerView) ID() string {
+	return "containers/" + v.System
+}
+
+func (v containerView) path() string {
+	return "containers/" + url.PathEscape(v.System) + ".html"
+}
+
+func (v containerView) parent(model Model) View {
+	return model.NewGenericSystemContextView()
+}
+
+func (v containerView) Elements() []string {
+	return concat(v.Containers, v.Systems, v.Personas)
+}
+
 type componentView struct {
 	title       string
 	description string
@@ -67,18 +127,71 @@ func (v componentView) Title() string {
 	return "[Components] " + v.title
 }
 
-func (m Model) NewSystemContextView(sysCtx SystemContext) View {
-	personas := make([]string, 0)
-	for k := range m.Personas {
-		personas = append(personas, k)
+func (v componentView) ID() string {
+	return "components/" + v.Container
+}
+
+func (v componentView) path() string {
+	return "components/" + url.PathEscape(v.Container) + ".html"
+}
+
+func (v componentView) parent(model Model) View {
+	cont, ok := model.Containers[v.Container]
+	if !ok {
+		return model.NewGenericSystemContextView()
+	}
+	sys, ok := model.Systems[cont.System]
+	if !ok {
+		return model.NewGenericSystemContextView()
+	}
+	return model.NewContainerView(sys)
+}
+
+func (v componentView) Elements() []string {
+	return concat(v.Components, v.Containers, v.Systems)
+}
+
+func (v systemContextView) boundary() string {
+	if v.generic {
+		return "All systems of the project"
 	}
+	return "Core systems of the system context"
+}
+
+func (v containerView) boundary() string {
+	return "Containers of the system " + v.System
+}
+
+func (v componentView) boundary() string {
+	return "Components of the container " + v.Container
+}
 
+func concat(lists ...[]string) []string {
+	all := make([]string, 0)
+	for _, l := range lists {
+		all = append(all, l...)
+	}
+	return all
+}
+
+// sortedSet returns the distinct names of a list in lexical order. Views
+// only contain sorted names, so that their graphs are always rendered the
+// same way.
+func sortedSet(names []string) []string {
+	set := make(map[string]bool)
+	for _, name := range names {
+		set[name] = true
+	}
+	return sortedKeys(set)
+}
+
+func (m Model) NewSystemContextView(sysCtx SystemContext) View {
 	return systemContextView{
 		title:           sysCtx.Name,
 		description:     sysCtx.Description,
 		CoreSystems:     sysCtx.CoreSystems,
 		ExternalSystems: sysCtx.ExternalSystems,
-		Personas:        personas,
+		Personas:        sortedKeys(m.Personas),
 	}
 
 }
@@ -88,7 +201,8 @@ func (m Model) NewContainerView(sys System) View {
 	systems := make([]string, 0)
 	personas := make([]string, 0)
 
-	for k, c := range m.Containers {
+	for _, k := range sortedKeys(m.Containers) {
+		c := m.Containers[k]
 		if c.System != sys.Name {
 			continue
 		}
@@ -118,8 +232,8 @@ func (m Model) NewContainerView(sys System) View {
 		description: sys.Description,
 		System:      sys.Name,
 		Containers:  containers,
-		Systems:     systems,
-		Personas:    personas,
+		Systems:     sortedSet(systems),
+		Personas:    sortedSet(personas),
 	}
 }
 
@@ -128,7 +242,8 @@ func (m Model) NewComponentView(cont Container) View {
 	containers := make([]string, 0)
 	systems := make([]string, 0)
 
-	for k, c := range m.Components {
+	for _, k := range sortedKeys(m.Components) {
+		c := m.Components[k]
 		if c.Container != cont.Name {
 			continue
 		}
@@ -156,28 +271,97 @@ func (m Model) NewComponentView(cont Container) View {
 		title:       cont.Name,
 		description: cont.Description,
 		Container:   cont.Name,
-		Containers:  containers,
+		Containers:  sortedSet(containers),
 		Components:  components,
-		Systems:     systems,
+		Systems:     sortedSet(systems),
 	}
 }
 
 func (m Model) NewGenericSystemContextView() View {
-	systems := make([]string, 0)
-	for k := range m.Systems {
-		systems = append(systems, k)
-	}
-
-	personas := make([]string, 0)
-	for k := range m.Personas {
-		personas = append(personas, k)
-	}
-
 	return systemContextView{
 		title:           "System Context Diagram",
 		description:     "The complete system context diagram, containing all systems of the current project.",
-		CoreSystems:     systems,
+		CoreSystems:     sortedKeys(m.Systems),
 		ExternalSystems: []string{},
-		Personas:        personas,
+		Personas:        sortedKeys(m.Personas),
+		generic:         true,
+	}
+}
+
+// Views returns all views of the model in navigation order: the generic
+// system context view, all system contexts, and for every system its
+// container view followed by the component views of its containers.
+func (m Model) Views() []View {
+	views := []View{m.NewGenericSystemContextView()}
+	for _, name := range sortedKeys(m.SystemContexts) {
+		views = append(views, m.NewSystemContextView(m.SystemContexts[name]))
+	}
+
+	seen := make(map[string]bool)
+	for _, sysName := range sortedKeys(m.Systems) {
+		views = append(views, m.NewContainerView(m.Systems[sysName]))
+		for _, contName := range m.systemContainers(sysName) {
+			views = append(views, m.NewComponentView(m.Containers[contName]))
+			seen[contName] = true
+		}
+	}
+
+	// containers of undefined systems are appended at the end, so that
+	// every view is reachable.
+	for _, contName := range sortedKeys(m.Containers) {
+		if !seen[contName] {
+			views = append(views, m.NewComponentView(m.Containers[contName]))
+		}
+	}
+	return views
+}
+
+// systemContainers returns the sorted names of all containers of a system.
+func (m Model) systemContainers(system string) []string {
+	names := make([]string, 0)
+	for name, c := range m.Containers {
+		if c.System == system {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// View returns the view with the given ID, as returned by View.ID.
+func (m Model) View(id string) (View, bool) {
+	i := strings.Index(id, "/")
+	if i == -1 {
+		return nil, false
+	}
+	kind, name := id[:i], id[i+1:]
+	switch kind {
+	case "contexts":
+		if name == genericContext {
+			return m.NewGenericSystemContextView(), true
+		}
+		sysCtx, ok := m.SystemContexts[name]
+		if !ok {
+			return nil, false
+		}
+		return m.NewSystemContextView(sysCtx), true
+	case "containers":
+		sys, ok := m.Systems[name]
+		if !ok {
+			return nil, false
+		}
+		return m.NewContainerView(sys), true
+	case "components":
+		cont, ok := m.Containers[name]
+		if !ok {
+			return nil, false
+		}
+		return m.NewComponentView(cont), true
+	case "impact":
+		if _, _, ok := m.Element(name); !ok {
+			return nil, false
+		}
+		return m.NewImpactView(name), true
 	}
+	return nil, false
 }
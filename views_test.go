@@ -0,0 +1,90 @@
+// Copyright (c) 2017, David Url
+// Use of this source code is governed by the
+// GNU General Public License Version 2
+// which can be found in the LICENSE file.
+
+package blueprint
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestViewsOrder(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	paths := make([]string, 0)
+	for _, v := range m.Views() {
+		paths = append(paths, v.path())
+	}
+	expectedPaths := []string{
+		"contexts/index.html",
+		"contexts/example.com%20System.html",
+		"containers/Hackernews.html",
+		"containers/example.com%20Blog.html",
+		"components/Database.html",
+		"components/Web%20App.html",
+	}
+	assertEqual(t, expectedPaths, paths, "view paths do not match")
+}
+
+func TestSiteNav(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	nav := siteNav(m, m.Views(), "components/Web%20App.html")
+	assertEqual(t, 1, len(nav), "1 root view expected")
+	assertEqual(t, "contexts/index.html", nav[0].URL, "root view does not match")
+	assertEqual(t, 3, len(nav[0].Children), "3 child views of root expected")
+
+	blog := nav[0].Children[2]
+	assertEqual(t, "containers/example.com%20Blog.html", blog.URL, "system view does not match")
+	assertEqual(t, 2, len(blog.Children), "2 component views expected")
+	assertEqual(t, true, blog.Children[1].Active, "component view should be active")
+}
+
+func TestPageNav(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	p := page{}
+	p.setNav(m.NewComponentView(m.Containers["Database"]), m, m.Views())
+
+	crumbs := make([]string, 0)
+	for _, l := range p.Breadcrumbs {
+		crumbs = append(crumbs, l.URL)
+	}
+	expectedCrumbs := []string{"index.html", "contexts/index.html", "containers/example.com%20Blog.html"}
+	assertEqual(t, expectedCrumbs, crumbs, "breadcrumbs do not match")
+	assertEqual(t, "containers/example.com%20Blog.html", p.Prev.URL, "previous view does not match")
+	assertEqual(t, "components/Web%20App.html", p.Next.URL, "next view does not match")
+}
+
+func TestRenderHTMLIndex(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	buf := new(bytes.Buffer)
+	err = RenderHTMLIndex(buf, m)
+	assertEqual(t, nil, err, "RenderHTMLIndex returned an error")
+	assertEqual(t, true, strings.Contains(buf.String(), `href="components/Web%20App.html"`),
+		"index does not link component view")
+}
+
+func TestViewByID(t *testing.T) {
+	m, err := Parse("test/ok")
+	assertEqual(t, nil, err, "Parse returned an error")
+
+	for _, v := range m.Views() {
+		found, ok := m.View(v.ID())
+		assertEqual(t, true, ok, "view not found: "+v.ID())
+		assertEqual(t, v.path(), found.path(), "found view does not match")
+	}
+
+	_, ok := m.View("components/Unknown")
+	assertEqual(t, false, ok, "unknown view should not be found")
+	_, ok = m.View("unknown")
+	assertEqual(t, false, ok, "invalid view ID should not be found")
+}